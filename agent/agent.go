@@ -1,23 +1,26 @@
 package main
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"net/url"
 	"os"
 	"runtime"
+	"sync"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
 	"github.com/shellhub-io/shellhub/agent/pkg/keygen"
 	"github.com/shellhub-io/shellhub/agent/pkg/sysinfo"
+	"github.com/shellhub-io/shellhub/agent/sshd"
 	"github.com/shellhub-io/shellhub/pkg/api/client"
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/shellhub-io/shellhub/pkg/revdial"
+	"github.com/sirupsen/logrus"
 )
 
 type Agent struct {
 	opts          *ConfigOptions
-	pubKey        *rsa.PublicKey
+	pubKey        crypto.PublicKey
 	Identity      *models.DeviceIdentity
 	Info          *models.DeviceInfo
 	authData      *models.DeviceAuthResponse
@@ -25,9 +28,13 @@ type Agent struct {
 	serverInfo    *models.Info
 	serverAddress *url.URL
 	sessions      []string
+	connection    connectionState
+	mu            sync.RWMutex
 }
 
-func NewAgent(opts *ConfigOptions) (*Agent, error) {
+// NewAgent creates the agent of the configuration, whose client to the server
+// is also created with the extra options.
+func NewAgent(opts *ConfigOptions, extra ...client.Opt) (*Agent, error) {
 	a := &Agent{}
 
 	serverAddress, err := url.Parse(opts.ServerAddress)
@@ -37,9 +44,25 @@ func NewAgent(opts *ConfigOptions) (*Agent, error) {
 
 	a.serverAddress = serverAddress
 
+	clientOpts := []client.Opt{client.WithURL(serverAddress)}
+
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid proxy address")
+		}
+
+		clientOpts = append(clientOpts, client.WithProxy(proxy))
+	}
+
+	cli := client.NewClient(append(clientOpts, extra...)...)
+	if cli == nil {
+		return nil, errors.Wrap(client.ErrUnsupportedProxy, "invalid proxy address")
+	}
+
 	return &Agent{
 		opts: opts,
-		cli:  client.NewClient(client.WithURL(serverAddress)),
+		cli:  cli,
 	}, nil
 }
 
@@ -74,7 +97,7 @@ func (a *Agent) initialize() error {
 
 func (a *Agent) generatePrivateKey() error {
 	if _, err := os.Stat(a.opts.PrivateKey); os.IsNotExist(err) {
-		err := keygen.GeneratePrivateKey(a.opts.PrivateKey)
+		err := keygen.GenerateKey(a.opts.PrivateKey, a.opts.PrivateKeyType)
 		if err != nil {
 			return err
 		}
@@ -92,14 +115,12 @@ func (a *Agent) readPublicKey() error {
 
 // generateDeviceIdentity generates device identity.
 func (a *Agent) generateDeviceIdentity() error {
-	iface, err := sysinfo.PrimaryInterface()
+	identity, err := newDeviceIdentity(a.opts)
 	if err != nil {
 		return err
 	}
 
-	a.Identity = &models.DeviceIdentity{
-		MAC: iface.HardwareAddr.String(),
-	}
+	a.Identity = identity
 
 	return nil
 }
@@ -122,6 +143,70 @@ func (a *Agent) loadDeviceInfo() error {
 	return nil
 }
 
+// loadInventory refreshes the hardware and system inventory of the device
+// info. The items which cannot be read are left empty.
+//
+// The inventory is read into a copy of the device info, which replaces the
+// current one, since the diagnostics of the device read it concurrently.
+func (a *Agent) loadInventory() {
+	info := *a.Info
+
+	logError := func(item string, err error) {
+		logrus.WithFields(logrus.Fields{"item": item, "err": err}).Debug("Failed to read device inventory")
+	}
+
+	var err error
+
+	if info.Kernel, err = sysinfo.KernelVersion(); err != nil {
+		logError("kernel", err)
+	}
+
+	info.CPU = nil
+	if cpu, err := sysinfo.GetCPU(); err == nil {
+		info.CPU = &models.DeviceCPU{Model: cpu.Model, Count: cpu.Count}
+	} else {
+		logError("cpu", err)
+	}
+
+	if info.Memory, err = sysinfo.MemoryTotal(); err != nil {
+		logError("memory", err)
+	}
+
+	info.Disks = nil
+	if disks, err := sysinfo.Disks(); err == nil {
+		for _, disk := range disks {
+			info.Disks = append(info.Disks, models.DeviceDisk{Name: disk.Name, Size: disk.Size})
+		}
+	} else {
+		logError("disks", err)
+	}
+
+	if info.Uptime, err = sysinfo.Uptime(); err != nil {
+		logError("uptime", err)
+	}
+
+	info.Interfaces = nil
+	if interfaces, err := sysinfo.Interfaces(); err == nil {
+		for _, iface := range interfaces {
+			info.Interfaces = append(info.Interfaces, models.DeviceInterface{
+				Name:      iface.Name,
+				MAC:       iface.MAC,
+				Addresses: iface.Addresses,
+			})
+		}
+	} else {
+		logError("interfaces", err)
+	}
+
+	if info.Timezone, err = sysinfo.Timezone(); err != nil {
+		logError("timezone", err)
+	}
+
+	a.mu.Lock()
+	a.Info = &info
+	a.mu.Unlock()
+}
+
 // checkUpdate check for agent updates.
 func (a *Agent) checkUpdate() (*semver.Version, error) {
 	info, err := a.cli.GetInfo(AgentVersion)
@@ -142,22 +227,75 @@ func (a *Agent) probeServerInfo() error {
 
 // authorize send auth request to the server.
 func (a *Agent) authorize() error {
+	publicKey, err := keygen.EncodePublicKeyToPem(a.pubKey)
+	if err != nil {
+		return err
+	}
+
+	a.loadInventory()
+
+	a.mu.RLock()
+	opts := a.opts
+	sessions := a.sessions
+	a.mu.RUnlock()
+
+	containers, err := sshd.ListContainers()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to list containers")
+	}
+
 	authData, err := a.cli.AuthDevice(&models.DeviceAuthRequest{
-		Info:     a.Info,
-		Sessions: a.sessions,
+		Info:       a.Info,
+		Sessions:   sessions,
+		Connection: a.connectionStats(),
+		Containers: containers,
 		DeviceAuth: &models.DeviceAuth{
-			Hostname:  a.opts.PreferredHostname,
+			Hostname:  opts.PreferredHostname,
 			Identity:  a.Identity,
-			TenantID:  a.opts.TenantID,
-			PublicKey: string(keygen.EncodePublicKeyToPem(a.pubKey)),
+			TenantID:  opts.TenantID,
+			PublicKey: string(publicKey),
 		},
 	})
+	if err != nil {
+		return err
+	}
 
+	a.mu.Lock()
 	a.authData = authData
+	a.mu.Unlock()
 
-	return err
+	return nil
 }
 
 func (a *Agent) newReverseListener() (*revdial.Listener, error) {
-	return a.cli.NewReverseListener(a.authData.Token)
+	a.mu.RLock()
+	token := a.authData.Token
+	a.mu.RUnlock()
+
+	return a.cli.NewReverseListener(token)
+}
+
+// config returns the current configuration. The configuration is never
+// modified once set, since it is replaced as a whole when reloaded.
+func (a *Agent) config() *ConfigOptions {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.opts
+}
+
+// setConfig replaces the configuration by the reloaded one.
+func (a *Agent) setConfig(opts *ConfigOptions) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.opts = opts
+}
+
+// setSessions sets the active sessions reported to the server.
+func (a *Agent) setSessions(sessions []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sessions = sessions
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/shellhub-io/shellhub/agent/sshd"
+	"github.com/sirupsen/logrus"
+)
+
+// breakGlassRefreshInterval is the age of the cached break-glass keys after
+// which they are fetched again from the server.
+const breakGlassRefreshInterval = 10 * time.Minute
+
+// breakGlassCache returns the path to the break-glass state file.
+func breakGlassCache(opts *ConfigOptions) string {
+	if opts.BreakGlassCache != "" {
+		return opts.BreakGlassCache
+	}
+
+	return filepath.Join(filepath.Dir(opts.PrivateKey), "break-glass.json")
+}
+
+// syncBreakGlass reports the break-glass logins made while the server was
+// unreachable and refreshes the cached keys when they are stale.
+func (a *Agent) syncBreakGlass(bg *sshd.BreakGlass) {
+	a.mu.RLock()
+	token := a.authData.Token
+	a.mu.RUnlock()
+
+	if logins := bg.Pending(); len(logins) > 0 {
+		if err := a.cli.ReportBreakGlassLogins(logins, token); err != nil {
+			logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to report the break-glass logins")
+		} else if err := bg.Reported(len(logins)); err != nil {
+			logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to save the break-glass state")
+		}
+	}
+
+	if !bg.Stale(breakGlassRefreshInterval) {
+		return
+	}
+
+	keys, err := a.cli.GetBreakGlassKeys(token)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to fetch the break-glass keys")
+
+		return
+	}
+
+	if err := bg.Update(keys); err != nil {
+		logrus.WithFields(logrus.Fields{"err": err}).Error("Failed to update the break-glass keys")
+	}
+}
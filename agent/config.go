@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigFile is the configuration file loaded when none is specified
+// through the --config flag. It is ignored if it does not exist.
+const DefaultConfigFile = "/etc/shellhub/agent.yaml"
+
+// configFileEnv holds the environment variables set from the configuration
+// file, so they can be replaced when the file is reloaded.
+var configFileEnv []string
+
+// LoadConfigOptions loads the agent configuration. The keys of the
+// configuration file are the same as the environment variables without the
+// SHELLHUB_ prefix (e.g. server_address) and the file can be written either
+// in YAML or TOML (.toml extension).
+//
+// The precedence, from highest to lowest, is: SHELLHUB_* environment
+// variables, unprefixed environment variables, the configuration file and
+// the default values.
+func LoadConfigOptions(path string) (*ConfigOptions, error) {
+	if err := applyConfigFile(path); err != nil {
+		return nil, err
+	}
+
+	opts := &ConfigOptions{}
+
+	// Process unprefixed env vars for backward compatibility
+	envconfig.Process("", opts) // nolint:errcheck
+
+	if err := envconfig.Process("shellhub", opts); err != nil {
+		return nil, err
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// validate checks the settings which would make the agent fail at runtime,
+// so an invalid configuration is rejected as it is loaded or reloaded.
+func (o *ConfigOptions) validate() error {
+	if o.KeepAliveInterval <= 0 {
+		return fmt.Errorf("invalid keepalive_interval %d: it must be greater than zero", o.KeepAliveInterval)
+	}
+
+	if o.PersistentSessionTimeout < 0 {
+		return fmt.Errorf("invalid persistent_session_timeout %d: it must not be negative", o.PersistentSessionTimeout)
+	}
+
+	if o.BreakGlassKeysTTL <= 0 {
+		return fmt.Errorf("invalid break_glass_keys_ttl %d: it must be greater than zero", o.BreakGlassKeysTTL)
+	}
+
+	return nil
+}
+
+// applyConfigFile exports the settings of the configuration file as
+// environment variables, unless they are already set in the environment.
+func applyConfigFile(path string) error {
+	for _, name := range configFileEnv {
+		os.Unsetenv(name) // nolint:errcheck
+	}
+
+	configFileEnv = nil
+
+	if path == "" {
+		return nil
+	}
+
+	values, err := readConfigFile(path)
+	if err != nil {
+		// The default configuration file is optional
+		if os.IsNotExist(err) && path == DefaultConfigFile {
+			return nil
+		}
+
+		return err
+	}
+
+	keys := configKeys()
+
+	for key, value := range values {
+		key = strings.ToLower(key)
+		if !keys[key] {
+			logrus.WithFields(logrus.Fields{
+				"key":  key,
+				"file": path,
+			}).Warning("Unknown configuration key")
+
+			continue
+		}
+
+		name := fmt.Sprintf("SHELLHUB_%s", strings.ToUpper(key))
+
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(strings.ToUpper(key)); ok {
+			continue
+		}
+
+		if err := os.Setenv(name, configValue(value)); err != nil {
+			return err
+		}
+
+		configFileEnv = append(configFileEnv, name)
+	}
+
+	return nil
+}
+
+// configValue formats a value of the configuration file as the environment
+// variables parsed by envconfig, whose lists are comma separated.
+func configValue(value interface{}) string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	items := make([]string, len(list))
+	for i, item := range list {
+		items[i] = fmt.Sprint(item)
+	}
+
+	return strings.Join(items, ",")
+}
+
+func readConfigFile(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+
+	if filepath.Ext(path) == ".toml" {
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		return values, nil
+	}
+
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// configKeys returns the keys accepted in the configuration file.
+func configKeys() map[string]bool {
+	keys := make(map[string]bool)
+
+	t := reflect.TypeOf(ConfigOptions{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("envconfig"); key != "" {
+			keys[key] = true
+		}
+	}
+
+	return keys
+}
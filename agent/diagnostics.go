@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shellhub-io/shellhub/agent/selfupdater"
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the secrets of the configuration in the diagnostics, as
+// done by the url package for the passwords of the URLs.
+const redacted = "xxxxx"
+
+// diagnosticsFile is a file of the diagnostics bundle.
+type diagnosticsFile struct {
+	name string
+	data []byte
+}
+
+// collectDiagnostics replies with a tar bundle holding the recent logs, the
+// configuration, the system information, the connection stats and the state
+// of the updater of the agent, requested by the server to troubleshoot the
+// device.
+func collectDiagnostics(w http.ResponseWriter, a *Agent, logs *logBuffer, status func() *Status, updater selfupdater.Updater) {
+	a.mu.RLock()
+	info := a.Info
+	identity := a.Identity
+	a.mu.RUnlock()
+
+	updaterState, err := updater.State()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to read the updater state")
+	}
+
+	files := []diagnosticsFile{{name: "logs.txt", data: logs.Bytes()}}
+
+	for _, file := range []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "config.json", value: redactConfig(a.config())},
+		{name: "sysinfo.json", value: map[string]interface{}{"info": info, "identity": identity}},
+		{name: "connection.json", value: status()},
+		{name: "updater.json", value: updaterState},
+	} {
+		data, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		files = append(files, diagnosticsFile{name: file.name, data: data})
+	}
+
+	logrus.Info("Diagnostics collected")
+
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	now := time.Now()
+
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.data)),
+			ModTime: now,
+		}); err != nil {
+			return
+		}
+
+		if _, err := tw.Write(file.data); err != nil {
+			return
+		}
+	}
+
+	tw.Close()
+}
+
+// redactConfig returns a copy of the configuration without its secrets.
+func redactConfig(opts *ConfigOptions) *ConfigOptions {
+	config := *opts
+
+	if config.SingleUserPassword != "" {
+		config.SingleUserPassword = redacted
+	}
+
+	if proxy, err := url.Parse(config.Proxy); err == nil && proxy.User != nil {
+		if _, ok := proxy.User.Password(); ok {
+			proxy.User = url.UserPassword(proxy.User.Username(), redacted)
+			config.Proxy = proxy.String()
+		}
+	} else if err != nil {
+		config.Proxy = redacted
+	}
+
+	return &config
+}
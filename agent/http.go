@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// proxyHTTP forwards a HTTP request made by the server, websockets included,
+// to a local port of the device. Only the ports in the allowed list can be
+// reached.
+func proxyHTTP(w http.ResponseWriter, r *http.Request, allowed []int) {
+	vars := mux.Vars(r)
+
+	port, err := strconv.Atoi(vars["port"])
+	if err != nil || !allowedPort(port, allowed) {
+		logrus.WithFields(logrus.Fields{
+			"port": vars["port"],
+		}).Warning("HTTP proxy to a port not allowed")
+
+		http.Error(w, "port not allowed", http.StatusForbidden)
+
+		return
+	}
+
+	host := net.JoinHostPort("localhost", vars["port"])
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = host
+			req.Host = host
+
+			if req.URL.Path == "" {
+				req.URL.Path = "/"
+			}
+		},
+	}
+
+	http.StripPrefix("/http/"+vars["port"], proxy).ServeHTTP(w, r)
+}
+
+func allowedPort(port int, allowed []int) bool {
+	for _, p := range allowed {
+		if p == port {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shellhub-io/shellhub/agent/pkg/sysinfo"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrNoDeviceIdentity = errors.New("no device identity source available")
+
+// identitySources are the sources tried, in order, when no identity source
+// is configured. The MAC address comes first so devices keep the identity
+// they had before the other sources were supported.
+var identitySources = []string{
+	models.DeviceIdentitySourceMAC,
+	models.DeviceIdentitySourceMachineID,
+	models.DeviceIdentitySourceProductUUID,
+}
+
+// newDeviceIdentity builds the device identity from the configured source.
+func newDeviceIdentity(opts *ConfigOptions) (*models.DeviceIdentity, error) {
+	identity := &models.DeviceIdentity{}
+
+	// The MAC address is always sent, when available, since servers which
+	// do not know the identity sources rely on it.
+	if iface, err := sysinfo.PrimaryInterface(); err == nil {
+		identity.MAC = iface.HardwareAddr.String()
+	}
+
+	if opts.DeviceIdentity != "" {
+		identity.Source = models.DeviceIdentitySourceCustom
+		identity.ID = opts.DeviceIdentity
+
+		return identity, nil
+	}
+
+	if opts.DeviceIdentitySource != "" {
+		id, err := readDeviceIdentity(opts.DeviceIdentitySource, identity.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s identity: %w", opts.DeviceIdentitySource, err)
+		}
+
+		identity.Source = opts.DeviceIdentitySource
+		identity.ID = id
+
+		return identity, nil
+	}
+
+	for _, source := range identitySources {
+		id, err := readDeviceIdentity(source, identity.MAC)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"source": source,
+				"err":    err,
+			}).Debug("Device identity source not available")
+
+			continue
+		}
+
+		identity.Source = source
+		identity.ID = id
+
+		return identity, nil
+	}
+
+	return nil, ErrNoDeviceIdentity
+}
+
+func readDeviceIdentity(source, mac string) (string, error) {
+	switch source {
+	case models.DeviceIdentitySourceMAC:
+		if mac == "" {
+			return "", sysinfo.ErrNoInterfaceFound
+		}
+
+		return mac, nil
+	case models.DeviceIdentitySourceMachineID:
+		return sysinfo.MachineID()
+	case models.DeviceIdentitySourceProductUUID:
+		return sysinfo.ProductUUID()
+	}
+
+	return "", fmt.Errorf("unknown identity source %q", source)
+}
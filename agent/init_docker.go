@@ -12,4 +12,7 @@ var AgentPlatform = "docker"
 func init() {
 	osauth.DefaultShadowFilename = "/host/etc/shadow"
 	sysinfo.DefaultOSReleaseFilename = "/host/etc/os-release"
+	sysinfo.DefaultMachineIDFilename = "/host/etc/machine-id"
+	sysinfo.DefaultTimezoneFilename = "/host/etc/timezone"
+	sysinfo.DefaultLocaltimeFilename = "/host/etc/localtime"
 }
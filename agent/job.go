@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/shellhub-io/shellhub/agent/sshd"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultJobTimeout is the time in seconds a job command is allowed to run
+// when the server does not set one.
+const defaultJobTimeout = 60
+
+// execJob runs the command of a job requested by the server as the OS user
+// configured on the device, and replies with its output once it finishes. The
+// server can not choose the user, so the jobs are refused when none is
+// configured.
+func execJob(w http.ResponseWriter, r *http.Request, server *sshd.Server, user string) {
+	if user == "" {
+		http.Error(w, "jobs are disabled on the device", http.StatusForbidden)
+
+		return
+	}
+
+	var cmd models.JobCommand
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if cmd.Timeout <= 0 {
+		cmd.Timeout = defaultJobTimeout
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"user":    user,
+		"command": cmd.Command,
+	})
+
+	log.Info("Job command started")
+
+	out, err := server.Exec(user, cmd.Command, time.Duration(cmd.Timeout)*time.Second)
+	if err != nil {
+		log.WithError(err).Warning("Failed to execute job command")
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, sshd.ErrUnknownUser) {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"exit_code": out.ExitCode,
+	}).Info("Job command finished")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out) // nolint:errcheck
+}
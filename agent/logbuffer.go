@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLogBufferSize is the number of log entries kept by the agent to be
+// included in the diagnostics of the device.
+const defaultLogBufferSize = 1000
+
+// logBuffer is a logrus hook which keeps the most recent log entries in
+// memory, so they can be uploaded on demand.
+type logBuffer struct {
+	mu        sync.Mutex
+	formatter logrus.Formatter
+	entries   [][]byte
+	next      int
+	full      bool
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true},
+		entries:   make([][]byte, size),
+	}
+}
+
+func (b *logBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (b *logBuffer) Fire(entry *logrus.Entry) error {
+	line, err := b.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The formatter may reuse its buffer, so the line is copied.
+	b.entries[b.next] = append([]byte(nil), line...)
+	b.next = (b.next + 1) % len(b.entries)
+
+	if b.next == 0 {
+		b.full = true
+	}
+
+	return nil
+}
+
+// Bytes returns the buffered log entries, from the oldest to the newest.
+func (b *logBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	if b.full {
+		for _, line := range b.entries[b.next:] {
+			buf.Write(line)
+		}
+	}
+
+	for _, line := range b.entries[:b.next] {
+		buf.Write(line)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics serves the Prometheus metrics of the agent at address. The
+// status of the agent is read on each scrape.
+func serveMetrics(address string, fn func() *Status) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "shellhub",
+			Subsystem: "agent",
+			Name:      "connected",
+			Help:      "Whether the agent is connected to the server.",
+		}, func() float64 {
+			if fn().Connected {
+				return 1
+			}
+
+			return 0
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "shellhub",
+			Subsystem: "agent",
+			Name:      "connected_seconds",
+			Help:      "Time in seconds since the agent connected to the server.",
+		}, func() float64 {
+			return float64(fn().ConnectedTime)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "shellhub",
+			Subsystem: "agent",
+			Name:      "reconnects_total",
+			Help:      "Number of times the agent reconnected to the server.",
+		}, func() float64 {
+			return float64(fn().Reconnects)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "shellhub",
+			Subsystem: "agent",
+			Name:      "active_sessions",
+			Help:      "Number of sessions in progress on the device.",
+		}, func() float64 {
+			return float64(len(fn().Sessions))
+		}),
+	)
+
+	router := http.NewServeMux()
+	router.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	return http.ListenAndServe(address, router) //nolint:gosec
+}
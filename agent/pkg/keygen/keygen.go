@@ -1,6 +1,10 @@
 package keygen
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,12 +14,29 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
 )
 
-var ErrPemDecode = errors.New("PEM decode error")
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+var (
+	ErrPemDecode          = errors.New("PEM decode error")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
 
+// GeneratePrivateKey generates a RSA private key.
 func GeneratePrivateKey(filename string) error {
-	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	return GenerateKey(filename, KeyTypeRSA)
+}
+
+// GenerateKey generates a private key of the given type: rsa, ecdsa or
+// ed25519.
+func GenerateKey(filename, keyType string) error {
+	block, err := generatePEMBlock(keyType)
 	if err != nil {
 		return err
 	}
@@ -37,12 +58,7 @@ func GeneratePrivateKey(filename string) error {
 
 	defer f.Close()
 
-	privateKey := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
-	}
-
-	err = pem.Encode(f, privateKey)
+	err = pem.Encode(f, block)
 	if err != nil {
 		return err
 	}
@@ -50,28 +66,100 @@ func GeneratePrivateKey(filename string) error {
 	return f.Sync()
 }
 
-func ReadPublicKey(filename string) (*rsa.PublicKey, error) {
+func generatePEMBlock(keyType string) (*pem.Block, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}, nil
+	case KeyTypeECDSA:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: data,
+		}, nil
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: data,
+		}, nil
+	}
+
+	return nil, ErrUnsupportedKeyType
+}
+
+// ReadPublicKey reads the public key of the private key stored in filename.
+func ReadPublicKey(filename string) (crypto.PublicKey, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(data)
-	if block == nil {
+	if block, _ := pem.Decode(data); block == nil {
 		return nil, ErrPemDecode
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	key, err := ssh.ParseRawPrivateKey(data)
 	if err != nil {
 		return nil, err
 	}
 
-	return &key.PublicKey, nil
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey, nil
+	case ed25519.PrivateKey:
+		return key.Public(), nil
+	case *ed25519.PrivateKey:
+		return key.Public(), nil
+	}
+
+	return nil, ErrUnsupportedKeyType
 }
 
-func EncodePublicKeyToPem(key *rsa.PublicKey) []byte {
+// EncodePublicKeyToPem encodes the public key in PEM format. RSA keys are
+// encoded in PKCS #1 form, as they always were, and the others in PKIX form.
+func EncodePublicKeyToPem(key crypto.PublicKey) ([]byte, error) {
+	if key, ok := key.(*rsa.PublicKey); ok {
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PUBLIC KEY",
+			Bytes: x509.MarshalPKCS1PublicKey(key),
+		}), nil
+	}
+
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
 	return pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PUBLIC KEY",
-		Bytes: x509.MarshalPKCS1PublicKey(key),
-	})
+		Type:  "PUBLIC KEY",
+		Bytes: data,
+	}), nil
 }
@@ -79,6 +79,28 @@ func LookupUser(username string) *User {
 	return &user
 }
 
+// LookupUserInFile looks up the user in the passwd file at path, which is not
+// necessarily the one of the host (e.g. the passwd file of a container).
+func LookupUserInFile(path, username string) (*User, error) {
+	passwdFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer passwdFile.Close()
+
+	entries, err := parsePasswdReader(passwdFile)
+	if err != nil {
+		return nil, err
+	}
+
+	user, found := entries[username]
+	if !found {
+		return nil, fmt.Errorf("user %s not found in %s", username, path)
+	}
+
+	return &user, nil
+}
+
 func parsePasswdReader(r io.Reader) (map[string]User, error) {
 	lines := bufio.NewReader(r)
 	entries := make(map[string]User)
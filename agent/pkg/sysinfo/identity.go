@@ -0,0 +1,41 @@
+package sysinfo
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+var (
+	DefaultMachineIDFilename   = "/etc/machine-id"
+	DefaultProductUUIDFilename = "/sys/class/dmi/id/product_uuid"
+)
+
+var ErrInvalidIdentity = errors.New("invalid identity")
+
+// MachineID returns the machine ID set by systemd or D-Bus.
+func MachineID() (string, error) {
+	return readIdentityFile(DefaultMachineIDFilename)
+}
+
+// ProductUUID returns the product UUID reported by the DMI firmware. It is
+// readable only by root.
+func ProductUUID() (string, error) {
+	return readIdentityFile(DefaultProductUUIDFilename)
+}
+
+func readIdentityFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	id := strings.ToLower(strings.TrimSpace(string(data)))
+
+	// Some firmwares and images ship with an unset identity
+	if strings.Trim(id, "0-") == "" {
+		return "", ErrInvalidIdentity
+	}
+
+	return id, nil
+}
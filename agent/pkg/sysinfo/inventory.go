@@ -0,0 +1,220 @@
+package sysinfo
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	DefaultProcPath          = "/proc"
+	DefaultBlockDevicesPath  = "/sys/block"
+	DefaultTimezoneFilename  = "/etc/timezone"
+	DefaultLocaltimeFilename = "/etc/localtime"
+)
+
+// sectorSize is the unit of the block devices size reported by sysfs.
+const sectorSize = 512
+
+type CPU struct {
+	Model string
+	Count int
+}
+
+type Disk struct {
+	Name string
+	Size uint64
+}
+
+type Interface struct {
+	Name      string
+	MAC       string
+	Addresses []string
+}
+
+// KernelVersion returns the release of the running kernel.
+func KernelVersion() (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(DefaultProcPath, "sys/kernel/osrelease"))
+
+	return strings.TrimSpace(string(data)), err
+}
+
+// GetCPU returns the model and the number of logical CPUs.
+func GetCPU() (*CPU, error) {
+	file, err := os.Open(filepath.Join(DefaultProcPath, "cpuinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cpu := &CPU{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "processor":
+			cpu.Count++
+		case "model name", "Hardware", "cpu model", "cpu":
+			// The key holding the model depends on the architecture
+			if cpu.Model == "" {
+				cpu.Model = value
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if cpu.Count == 0 {
+		cpu.Count = runtime.NumCPU()
+	}
+
+	return cpu, nil
+}
+
+// MemoryTotal returns the total usable memory in bytes.
+func MemoryTotal() (uint64, error) {
+	file, err := os.Open(filepath.Join(DefaultProcPath, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		total, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		// The value is reported in kB
+		return total * 1024, nil
+	}
+
+	return 0, scanner.Err()
+}
+
+// Disks returns the block devices and their size in bytes, ignoring the
+// loop and RAM devices.
+func Disks() ([]Disk, error) {
+	entries, err := ioutil.ReadDir(DefaultBlockDevicesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var disks []Disk
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "zram") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(DefaultBlockDevicesPath, name, "size"))
+		if err != nil {
+			continue
+		}
+
+		sectors, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil || sectors == 0 {
+			continue
+		}
+
+		disks = append(disks, Disk{Name: name, Size: sectors * sectorSize})
+	}
+
+	return disks, nil
+}
+
+// Uptime returns the time since boot in seconds.
+func Uptime() (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(DefaultProcPath, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(uptime), nil
+}
+
+// Interfaces returns the network interfaces, except the loopback ones, with
+// their addresses.
+func Interfaces() ([]Interface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Interface
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback > 0 {
+			continue
+		}
+
+		item := Interface{
+			Name: iface.Name,
+			MAC:  iface.HardwareAddr.String(),
+		}
+
+		addrs, err := iface.Addrs()
+		if err == nil {
+			for _, addr := range addrs {
+				item.Addresses = append(item.Addresses, addr.String())
+			}
+		}
+
+		list = append(list, item)
+	}
+
+	return list, nil
+}
+
+// Timezone returns the name of the system timezone (e.g. America/Sao_Paulo).
+func Timezone() (string, error) {
+	if data, err := ioutil.ReadFile(DefaultTimezoneFilename); err == nil {
+		if tz := strings.TrimSpace(string(data)); tz != "" {
+			return tz, nil
+		}
+	}
+
+	// The localtime is a link to the timezone file in the zoneinfo database
+	target, err := os.Readlink(DefaultLocaltimeFilename)
+	if err != nil {
+		return "", err
+	}
+
+	if i := strings.Index(target, "zoneinfo/"); i >= 0 {
+		return target[i+len("zoneinfo/"):], nil
+	}
+
+	return "", os.ErrNotExist
+}
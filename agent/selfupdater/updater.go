@@ -1,6 +1,8 @@
 package selfupdater
 
 import (
+	"time"
+
 	"github.com/Masterminds/semver"
 )
 
@@ -8,4 +10,16 @@ type Updater interface {
 	CurrentVersion() (*semver.Version, error)
 	ApplyUpdate(v *semver.Version) error
 	CompleteUpdate() error
+	State() (*State, error)
+}
+
+// State is the state of the updater, reported in the diagnostics of the
+// device.
+type State struct {
+	Version string `json:"version"`
+
+	// Pending is set while an update to this version is not confirmed yet.
+	Pending   string     `json:"pending,omitempty"`
+	Previous  string     `json:"previous,omitempty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
 }
@@ -16,9 +16,20 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	apiclient "github.com/shellhub-io/shellhub/pkg/api/client"
 	"github.com/shellhub-io/shellhub/pkg/dockerutils"
 )
 
+// SuperviseCommand is the argument the agent is started with to supervise an
+// update, which is only done by the native updater.
+const SuperviseCommand = "update-supervisor"
+
+// Supervise does nothing, as the updated container is supervised by the
+// previous one.
+func Supervise(pid int, executable string) error {
+	return nil
+}
+
 type dockerContainer struct {
 	info *types.ContainerJSON
 }
@@ -121,6 +132,25 @@ func (d *dockerUpdater) CompleteUpdate() error {
 	return nil
 }
 
+func (d *dockerUpdater) State() (*State, error) {
+	container, err := d.currentContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	_, state.Version = container.splitImageVersion()
+
+	// The parent container is removed once the update is completed, so it
+	// is only found while the update is pending.
+	if parent, err := d.parentContainer(); err == nil && parent != nil {
+		_, state.Previous = parent.splitImageVersion()
+		state.Pending = state.Version
+	}
+
+	return state, nil
+}
+
 func (d *dockerUpdater) getContainer(id string) (*dockerContainer, error) {
 	ctx := context.Background()
 
@@ -203,7 +233,7 @@ func (d *dockerUpdater) updateContainer(container *dockerContainer, image, name
 	return d.getContainer(clone.ID)
 }
 
-func NewUpdater(_ string) (Updater, error) {
+func NewUpdater(_ string, _ apiclient.Client) (Updater, error) {
 	api, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return nil, err
@@ -3,25 +3,518 @@
 package selfupdater
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/Masterminds/semver"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/sirupsen/logrus"
+)
+
+// PublicKey is the base64 encoded Ed25519 public key used to verify the
+// signature of the downloaded releases. This is injected using `-ldflags`
+// build option (e.g: `go build -ldflags "-X
+// github.com/shellhub-io/shellhub/agent/selfupdater.PublicKey=..."`).
+//
+// If not set, the native updates are disabled.
+var PublicKey string
+
+// GracePeriod is the time the updated agent has to reach the server before
+// the update is rolled back.
+var GracePeriod = 5 * time.Minute
+
+// SuperviseCommand is the argument the agent is started with to supervise an
+// update, as done by Supervise.
+const SuperviseCommand = "update-supervisor"
+
+var (
+	ErrMissingPublicKey = errors.New("missing public key to verify updates")
+	ErrMissingUpdateURL = errors.New("server does not advertise an update URL")
+	ErrInvalidSignature = errors.New("invalid update signature")
+	ErrInvalidManifest  = errors.New("update manifest does not match the release")
 )
 
+// manifest describes a release and is signed along with it, so an older
+// release or one of another platform can not be applied in its place.
+type manifest struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	// SHA256 is the hex encoded SHA-256 digest of the binary.
+	SHA256 string `json:"sha256"`
+}
+
+// updateState is persisted next to the executable while an update is not
+// confirmed, so the updated agent knows it must reach the server within the
+// grace period.
+type updateState struct {
+	Version   string    `json:"version"`
+	Previous  string    `json:"previous"`
+	AppliedAt time.Time `json:"applied_at"`
+	// Supervisor is the process id of the supervisor rolling back the
+	// update when the updated agent dies before confirming it.
+	Supervisor int `json:"supervisor,omitempty"`
+}
+
 type nativeUpdater struct {
-	version string
+	version    string
+	cli        client.Client
+	executable string
 }
 
 func (n *nativeUpdater) CurrentVersion() (*semver.Version, error) {
 	return semver.NewVersion(n.version)
 }
 
-func (n *nativeUpdater) ApplyUpdate(_ *semver.Version) error {
+func (n *nativeUpdater) ApplyUpdate(v *semver.Version) error {
+	if PublicKey == "" {
+		return ErrMissingPublicKey
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return ErrMissingPublicKey
+	}
+
+	info, err := n.cli.GetInfo(n.version)
+	if err != nil {
+		return err
+	}
+
+	if info.UpdateURL == "" {
+		return ErrMissingUpdateURL
+	}
+
+	url := strings.NewReplacer(
+		"{version}", v.Original(),
+		"{os}", runtime.GOOS,
+		"{arch}", runtime.GOARCH,
+	).Replace(info.UpdateURL)
+
+	logrus.WithFields(logrus.Fields{
+		"version": v.Original(),
+		"url":     url,
+	}).Info("Downloading agent update")
+
+	httpClient := n.cli.HTTPClient()
+
+	data, err := download(httpClient, url+".manifest")
+	if err != nil {
+		return err
+	}
+
+	signature, err := download(httpClient, url+".manifest.sig")
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, data, decodeSignature(signature)) {
+		return ErrInvalidSignature
+	}
+
+	binary, err := download(httpClient, url)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyManifest(data, v, binary); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(n.path("new"), binary, 0755); err != nil { //nolint:gosec
+		return err
+	}
+
+	// Keep the current executable to be restored if the new version is
+	// not able to reach the server.
+	os.Remove(n.path("old")) // nolint:errcheck
+	if err := os.Link(n.executable, n.path("old")); err != nil {
+		os.Remove(n.path("new")) // nolint:errcheck
+
+		return err
+	}
+
+	state := &updateState{
+		Version:   v.Original(),
+		Previous:  n.version,
+		AppliedAt: time.Now(),
+	}
+
+	if err := n.writeState(state); err != nil {
+		os.Remove(n.path("new")) // nolint:errcheck
+
+		return err
+	}
+
+	// The rename is atomic since both files are in the same directory, so
+	// the executable is never missing or partially written.
+	if err := os.Rename(n.path("new"), n.executable); err != nil {
+		os.Remove(n.path("state")) // nolint:errcheck
+
+		return err
+	}
+
+	// The new version may crash before it is able to confirm or roll back
+	// the update by itself, so the previous one is left supervising it.
+	supervisor, err := n.startSupervisor()
+	if err != nil {
+		n.restore()
+
+		return err
+	}
+
+	state.Supervisor = supervisor
+	if err := n.writeState(state); err != nil {
+		logrus.WithError(err).Warning("Failed to record the agent update supervisor")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"version": v.Original(),
+	}).Info("Agent updated, restarting")
+
+	if err := n.exec(); err != nil {
+		// The new executable could not be started (e.g. it is not built
+		// for the machine), so the running one is put back in place
+		// before a restart runs the broken one.
+		n.restore()
+
+		return err
+	}
+
 	return nil
 }
 
+// restore puts the previous executable back in place of the updated one.
+func (n *nativeUpdater) restore() {
+	if err := os.Rename(n.path("old"), n.executable); err != nil {
+		logrus.WithError(err).Error("Failed to restore the previous agent executable")
+	}
+
+	os.Remove(n.path("state")) // nolint:errcheck
+}
+
 func (n *nativeUpdater) CompleteUpdate() error {
+	state, err := n.readState()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	// The running version is not the one being updated to, so there is
+	// nothing to be confirmed.
+	if state.Version != n.version {
+		n.cleanup()
+
+		return nil
+	}
+
+	remaining := time.Until(state.AppliedAt.Add(GracePeriod))
+	if remaining <= 0 {
+		return n.rollback()
+	}
+
+	// The supervisor is a child of the agent, which must reap it once it
+	// exits.
+	if state.Supervisor > 0 {
+		if p, err := os.FindProcess(state.Supervisor); err == nil {
+			go p.Wait() // nolint:errcheck
+		}
+	}
+
+	go func() {
+		reached := make(chan struct{})
+		done := make(chan struct{})
+		defer close(done)
+
+		// The server is retried until the end of the grace period, so a
+		// brief outage does not roll back a good update.
+		go func() {
+			delay := time.Second
+
+			for {
+				_, err := n.cli.GetInfo(n.version)
+				if err == nil {
+					close(reached)
+
+					return
+				}
+
+				logrus.WithError(err).Warning("Failed to confirm the agent update, retrying")
+
+				select {
+				case <-done:
+					return
+				case <-time.After(delay):
+				}
+
+				if delay *= 2; delay > time.Minute {
+					delay = time.Minute
+				}
+			}
+		}()
+
+		select {
+		case <-reached:
+			logrus.WithFields(logrus.Fields{
+				"version": n.version,
+			}).Info("Agent update confirmed")
+
+			n.cleanup()
+		case <-time.After(remaining):
+			if err := n.rollback(); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (n *nativeUpdater) State() (*State, error) {
+	state := &State{Version: n.version}
+
+	update, err := n.readState()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+
+		return nil, err
+	}
+
+	state.Pending = update.Version
+	state.Previous = update.Previous
+	state.AppliedAt = &update.AppliedAt
+
+	return state, nil
+}
+
+// rollback restores the previous executable and restarts the agent.
+func (n *nativeUpdater) rollback() error {
+	logrus.WithFields(logrus.Fields{
+		"version": n.version,
+	}).Warning("Agent update could not reach the server, rolling back")
+
+	if err := os.Rename(n.path("old"), n.executable); err != nil {
+		return err
+	}
+
+	os.Remove(n.path("state")) // nolint:errcheck
+
+	return n.exec()
+}
+
+// startSupervisor starts the previous executable supervising the agent while
+// the update is not confirmed.
+func (n *nativeUpdater) startSupervisor() (int, error) {
+	cmd := exec.Command(n.path("old"), SuperviseCommand, strconv.Itoa(os.Getpid()), n.executable) //nolint:gosec
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	return cmd.Process.Pid, nil
+}
+
+// Supervise rolls back the update of the agent running as pid from the
+// executable when it dies before confirming the update, as the new version
+// crashing on start can not do it by itself. The restored version is started
+// by whoever restarts the agent.
+func Supervise(pid int, executable string) error {
+	n := &nativeUpdater{executable: executable}
+
+	state, err := n.readState()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	// The service manager may stop the supervisor as soon as the agent
+	// dies, so it is checked once more before exiting.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// The agent rolls back the update by itself at the end of the grace
+	// period, unless it hangs.
+	deadline := time.After(time.Until(state.AppliedAt.Add(GracePeriod + time.Minute)))
+
+	for {
+		select {
+		case <-signals:
+			if syscall.Kill(pid, 0) == syscall.ESRCH {
+				n.restoreCrashed(state)
+			}
+
+			return nil
+		case <-ticker.C:
+			if _, err := os.Stat(n.path("state")); os.IsNotExist(err) {
+				return nil
+			}
+
+			if syscall.Kill(pid, 0) == syscall.ESRCH {
+				n.restoreCrashed(state)
+
+				return nil
+			}
+		case <-deadline:
+			if _, err := os.Stat(n.path("state")); os.IsNotExist(err) {
+				return nil
+			}
+
+			n.restoreCrashed(state)
+
+			return syscall.Kill(pid, syscall.SIGTERM)
+		}
+	}
+}
+
+// restoreCrashed restores the previous executable in place of an update that
+// did not confirm it is working.
+func (n *nativeUpdater) restoreCrashed(state *updateState) {
+	if _, err := os.Stat(n.path("state")); os.IsNotExist(err) {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"version":  state.Version,
+		"previous": state.Previous,
+	}).Warning("Updated agent stopped before confirming the update, rolling back")
+
+	n.restore()
+}
+
+func (n *nativeUpdater) cleanup() {
+	os.Remove(n.path("old"))   // nolint:errcheck
+	os.Remove(n.path("state")) // nolint:errcheck
+}
+
+func (n *nativeUpdater) exec() error {
+	return syscall.Exec(n.executable, os.Args, os.Environ()) //nolint:gosec
+}
+
+func (n *nativeUpdater) path(suffix string) string {
+	return fmt.Sprintf("%s.%s", n.executable, suffix)
+}
+
+func (n *nativeUpdater) readState() (*updateState, error) {
+	data, err := ioutil.ReadFile(n.path("state"))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &updateState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (n *nativeUpdater) writeState(state *updateState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(n.path("state"), data, 0600)
+}
+
+// verifyManifest checks whether the signed manifest describes the binary as the
+// release of version v for the running platform.
+func verifyManifest(data []byte, v *semver.Version, binary []byte) error {
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return ErrInvalidManifest
+	}
+
+	version, err := semver.NewVersion(m.Version)
+	if err != nil || !version.Equal(v) {
+		return ErrInvalidManifest
+	}
+
+	if m.OS != runtime.GOOS || m.Arch != runtime.GOARCH {
+		return ErrInvalidManifest
+	}
+
+	digest := sha256.Sum256(binary)
+	if !strings.EqualFold(m.SHA256, hex.EncodeToString(digest[:])) {
+		return ErrInvalidManifest
+	}
+
 	return nil
 }
 
-func NewUpdater(version string) (Updater, error) {
-	return &nativeUpdater{version}, nil
+func download(httpClient *http.Client, url string) ([]byte, error) {
+	res, err := httpClient.Get(url) //nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, res.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(res.Body, 256<<20))
+}
+
+// decodeSignature accepts both raw and base64 encoded signatures.
+func decodeSignature(data []byte) []byte {
+	if len(data) == ed25519.SignatureSize {
+		return data
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil
+	}
+
+	return signature
+}
+
+func NewUpdater(version string, cli client.Client) (Updater, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nativeUpdater{
+		version:    version,
+		cli:        cli,
+		executable: executable,
+	}, nil
 }
@@ -0,0 +1,58 @@
+package sshd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// setupAgentForwarding exposes the SSH agent forwarded by the client to the
+// command through the SSH_AUTH_SOCK environment variable. The returned
+// function releases the agent socket and must be called once the command
+// finishes. The agent is not forwarded when the gateway restricted it.
+func setupAgentForwarding(session sshserver.Session, u *osauth.User, cmd *exec.Cmd) func() {
+	if !sshserver.AgentRequested(session) || restricted(session.Context(), models.SessionRestrictAgentForwarding) {
+		return func() {}
+	}
+
+	dir, err := ioutil.TempDir(filepath.Join(agentSocketRoot, os.TempDir()), "auth-agent")
+	if err != nil {
+		logrus.Warn(err)
+
+		return func() {}
+	}
+
+	path := filepath.Join(dir, "listener.sock")
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		logrus.Warn(err)
+		os.RemoveAll(dir) // nolint:errcheck
+
+		return func() {}
+	}
+
+	// The socket must be reachable only by the user who owns the session.
+	for _, name := range []string{dir, path} {
+		if err := os.Chown(name, int(u.UID), int(u.GID)); err != nil {
+			logrus.Warn(err)
+		}
+	}
+
+	go sshserver.ForwardAgentConnections(listener, session)
+
+	cmd.Env = append(cmd.Env, "SSH_AUTH_SOCK="+strings.TrimPrefix(path, agentSocketRoot))
+
+	return func() {
+		listener.Close()
+		os.RemoveAll(dir) // nolint:errcheck
+	}
+}
@@ -0,0 +1,386 @@
+package sshd
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// breakGlassLoginContextKey holds the login of the connection, recorded once
+// the connection is used.
+const breakGlassLoginContextKey = "break_glass_login"
+
+var (
+	ErrBreakGlassServerKey = errors.New("break-glass keys signed by an unknown server key")
+	ErrBreakGlassSignature = errors.New("invalid break-glass keys signature")
+)
+
+// breakGlassState is persisted to disk, so the keys and the logins not yet
+// reported survive the agent restarts while the server is unreachable.
+type breakGlassState struct {
+	// ServerKey is pinned when the keys are fetched for the first time,
+	// and the keys signed by any other key are rejected from then on.
+	ServerKey string                   `json:"server_key"`
+	Keys      *models.BreakGlassKeys   `json:"keys"`
+	Pending   []models.BreakGlassLogin `json:"pending"`
+}
+
+// BreakGlass holds the public keys accepted by the local SSH listener and the
+// logins made through it.
+type BreakGlass struct {
+	path      string
+	ttl       time.Duration
+	mu        sync.Mutex
+	state     breakGlassState
+	serverKey *rsa.PublicKey
+}
+
+// breakGlassLogin is the login of a connection to the local SSH listener.
+type breakGlassLogin struct {
+	once sync.Once
+}
+
+// NewBreakGlass loads the break-glass state kept at path. The cached keys are
+// discarded if their signature does not match the pinned server key, and
+// refused once older than ttl.
+func NewBreakGlass(path string, ttl time.Duration) (*BreakGlass, error) {
+	b := &BreakGlass{path: path, ttl: ttl}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &b.state); err != nil {
+		return nil, err
+	}
+
+	if b.state.ServerKey != "" {
+		if b.serverKey, err = parseServerKey(b.state.ServerKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.state.Keys != nil {
+		if err := b.verify(b.state.Keys); err != nil {
+			logrus.WithFields(logrus.Fields{"err": err, "path": path}).Warning("Discarding the cached break-glass keys")
+
+			b.state.Keys = nil
+		}
+	}
+
+	return b, nil
+}
+
+// Update replaces the cached keys by the ones fetched from the server.
+func (b *BreakGlass) Update(keys *models.BreakGlassKeys) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.serverKey == nil {
+		serverKey, err := parseServerKey(keys.ServerKey)
+		if err != nil {
+			return err
+		}
+
+		b.serverKey = serverKey
+		b.state.ServerKey = keys.ServerKey
+	} else if keys.ServerKey != b.state.ServerKey {
+		return ErrBreakGlassServerKey
+	}
+
+	if err := b.verify(keys); err != nil {
+		return err
+	}
+
+	b.state.Keys = keys
+
+	return b.save()
+}
+
+// Stale reports whether the cached keys are older than maxAge.
+func (b *BreakGlass) Stale(maxAge time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state.Keys == nil || time.Since(b.state.Keys.IssuedAt) > maxAge
+}
+
+// Pending returns the logins not yet reported to the server.
+func (b *BreakGlass) Pending() []models.BreakGlassLogin {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]models.BreakGlassLogin(nil), b.state.Pending...)
+}
+
+// Reported removes the first n pending logins, once reported to the server.
+func (b *BreakGlass) Reported(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > len(b.state.Pending) {
+		n = len(b.state.Pending)
+	}
+
+	b.state.Pending = b.state.Pending[n:]
+
+	return b.save()
+}
+
+// authorize checks whether the key is one of the cached keys allowed to log
+// into the device, which are refused once expired.
+func (b *BreakGlass) authorize(key ssh.PublicKey) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state.Keys == nil {
+		return false
+	}
+
+	if time.Since(b.state.Keys.IssuedAt) > b.ttl {
+		logrus.WithFields(logrus.Fields{"issued_at": b.state.Keys.IssuedAt}).Warning("Refusing the expired break-glass keys")
+
+		return false
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	for _, k := range b.state.Keys.Keys {
+		if k.Fingerprint != fingerprint {
+			continue
+		}
+
+		if k.Hostname == "" {
+			return true
+		}
+
+		ok, err := regexp.MatchString(k.Hostname, b.state.Keys.DeviceName)
+
+		return ok && err == nil
+	}
+
+	return false
+}
+
+// record logs the login of the connection and keeps it to be reported. The
+// login is recorded once per connection, whatever channels it opens, as they
+// are only opened after the authentication. The key of the login is the one
+// which authenticated the connection, since the public key handler is also
+// called for the keys the client just queries.
+func (b *BreakGlass) record(ctx sshserver.Context) {
+	l, ok := ctx.Value(breakGlassLoginContextKey).(*breakGlassLogin)
+	if !ok {
+		return
+	}
+
+	l.once.Do(func() {
+		if key, ok := ctx.Value(sshserver.ContextKeyPublicKey).(sshserver.PublicKey); ok {
+			b.addLogin(ctx, key)
+		}
+	})
+}
+
+// addLogin logs the login made with the key and keeps it to be reported.
+func (b *BreakGlass) addLogin(ctx sshserver.Context, key ssh.PublicKey) {
+	login := models.BreakGlassLogin{
+		Username:    ctx.User(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		LoggedAt:    time.Now(),
+	}
+
+	if host, _, err := net.SplitHostPort(ctx.RemoteAddr().String()); err == nil {
+		login.IPAddress = host
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user":        login.Username,
+		"fingerprint": login.Fingerprint,
+		"ip_address":  login.IPAddress,
+	}).Warning("Break-glass login")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state.Pending = append(b.state.Pending, login)
+
+	if err := b.save(); err != nil {
+		logrus.WithFields(logrus.Fields{"err": err, "path": b.path}).Error("Failed to save the break-glass login")
+	}
+}
+
+// portForwardingCallback applies the port forwarding setting of the namespace
+// signed along with the keys, since no gateway enforces it on the local
+// listener. The forwardings are denied when the setting is unknown.
+func (b *BreakGlass) portForwardingCallback(ctx sshserver.Context, host string, port uint32) bool {
+	b.mu.Lock()
+	allowed := b.state.Keys != nil && b.state.Keys.PortForwarding
+	b.mu.Unlock()
+
+	logrus.WithFields(logrus.Fields{
+		"user":    ctx.User(),
+		"host":    host,
+		"port":    port,
+		"allowed": allowed,
+	}).Info("Break-glass port forwarding requested")
+
+	return allowed
+}
+
+func (b *BreakGlass) verify(keys *models.BreakGlassKeys) error {
+	if b.serverKey == nil {
+		return ErrBreakGlassServerKey
+	}
+
+	data, err := keys.SignedData()
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(keys.Signature)
+	if err != nil {
+		return ErrBreakGlassSignature
+	}
+
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(b.serverKey, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrBreakGlassSignature
+	}
+
+	return nil
+}
+
+// save writes the state to a temporary file which is renamed over the
+// previous one, so it is never left partially written.
+func (b *BreakGlass) save() error {
+	data, err := json.Marshal(b.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(b.path), filepath.Base(b.path))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name()) // nolint:errcheck
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name()) // nolint:errcheck
+
+		return err
+	}
+
+	return os.Rename(tmp.Name(), b.path)
+}
+
+func parseServerKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, ErrBreakGlassServerKey
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrBreakGlassServerKey
+	}
+
+	return rsaKey, nil
+}
+
+// recordChannels wraps the channel handlers to record the login of the
+// connection opening the channels.
+func (b *BreakGlass) recordChannels(handlers map[string]sshserver.ChannelHandler) map[string]sshserver.ChannelHandler {
+	wrapped := make(map[string]sshserver.ChannelHandler, len(handlers))
+	for name, handler := range handlers {
+		handler := handler
+		wrapped[name] = func(srv *sshserver.Server, conn *ssh.ServerConn, newChan ssh.NewChannel, ctx sshserver.Context) {
+			b.record(ctx)
+			handler(srv, conn, newChan, ctx)
+		}
+	}
+
+	return wrapped
+}
+
+// recordRequests wraps the global request handlers, such as the remote port
+// forwardings, to record the login of the connection sending the requests.
+func (b *BreakGlass) recordRequests(handlers map[string]sshserver.RequestHandler) map[string]sshserver.RequestHandler {
+	wrapped := make(map[string]sshserver.RequestHandler, len(handlers))
+	for name, handler := range handlers {
+		handler := handler
+		wrapped[name] = func(ctx sshserver.Context, srv *sshserver.Server, req *ssh.Request) (bool, []byte) {
+			b.record(ctx)
+
+			return handler(ctx, srv, req)
+		}
+	}
+
+	return wrapped
+}
+
+// ListenAndServeBreakGlass serves the local SSH listener used to reach the
+// device when the server is unreachable. Only the public keys cached by bg
+// are accepted and every login is recorded to be reported to the server.
+func (s *Server) ListenAndServeBreakGlass(addr string, bg *BreakGlass) error {
+	srv := &sshserver.Server{
+		Addr: addr,
+		PublicKeyHandler: func(ctx sshserver.Context, key sshserver.PublicKey) bool {
+			if osauth.LookupUser(s.loginUser(ctx.User())) == nil || !bg.authorize(key) {
+				return false
+			}
+
+			// The handshake calls the handler sequentially, so the login
+			// is set before the channels are served concurrently.
+			if _, ok := ctx.Value(breakGlassLoginContextKey).(*breakGlassLogin); !ok {
+				ctx.SetValue(breakGlassLoginContextKey, &breakGlassLogin{})
+			}
+
+			return true
+		},
+		Handler:                       s.sessionHandler,
+		RequestHandlers:               bg.recordRequests(s.sshd.RequestHandlers),
+		ChannelHandlers:               bg.recordChannels(s.sshd.ChannelHandlers),
+		LocalPortForwardingCallback:   bg.portForwardingCallback,
+		ReversePortForwardingCallback: bg.portForwardingCallback,
+		SubsystemHandlers:             s.sshd.SubsystemHandlers,
+		ConnCallback:                  s.sshd.ConnCallback,
+		HostSigners:                   s.sshd.HostSigners,
+	}
+
+	logrus.WithFields(logrus.Fields{"address": addr}).Info("Break-glass SSH listener started")
+
+	return srv.ListenAndServe()
+}
@@ -12,6 +12,10 @@ import (
 	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
 )
 
+// agentSocketRoot is the directory where the root filesystem seen by the
+// user processes is mounted.
+const agentSocketRoot = ""
+
 func newCmd(u *osauth.User, shell, term, host string, command ...string) *exec.Cmd {
 	user, _ := user.Lookup(u.Username)
 	userGroups, _ := user.GroupIds()
@@ -42,3 +46,10 @@ func newCmd(u *osauth.User, shell, term, host string, command ...string) *exec.C
 
 	return cmd
 }
+
+// newSFTPCmd creates the command that serves the SFTP subsystem. The agent
+// binary is re-executed with the credentials of the user, so the files are
+// accessed with the same permissions as a regular login.
+func newSFTPCmd(u *osauth.User, host string) (*exec.Cmd, error) {
+	return newCmd(u, "", "", host, "/proc/self/exe", "sftp"), nil
+}
@@ -7,10 +7,15 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"sync"
 
 	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
 )
 
+// agentSocketRoot is the directory where the host root filesystem, seen by
+// the user processes, is mounted inside the container.
+const agentSocketRoot = "/host"
+
 func newCmd(u *osauth.User, shell, term, host string, command ...string) *exec.Cmd {
 	nscommand, _ := nsenterCommandWrapper(u.UID, u.GID, fmt.Sprintf("/host/%s", u.HomeDir), command...)
 
@@ -59,6 +64,12 @@ func nsenterCommandWrapper(uid, gid uint32, home string, command ...string) ([]s
 		return nil, err
 	}
 
+	return append(getWrappedCommand(namespaceArgs(1), uid, gid, home), command...), nil
+}
+
+// namespaceArgs returns the nsenter arguments to enter the namespaces of the
+// process available in the kernel.
+func namespaceArgs(pid int) []string {
 	paths := map[string]string{
 		"mnt":    "-m",
 		"uts":    "-u",
@@ -71,12 +82,53 @@ func nsenterCommandWrapper(uid, gid uint32, home string, command ...string) ([]s
 
 	args := []string{}
 	for path, params := range paths {
-		if _, err := os.Stat(fmt.Sprintf("/proc/1/ns/%s", path)); err != nil {
+		if _, err := os.Stat(fmt.Sprintf("/proc/%d/ns/%s", pid, path)); err != nil {
 			continue
 		}
 
 		args = append(args, params)
 	}
 
-	return append(getWrappedCommand(args, uid, gid, home), command...), nil
+	return args
+}
+
+// agentBinary is a handle to the agent executable kept open to be inherited
+// by the SFTP command, since the binary is not reachable from the host mount
+// namespace where the command runs. The binary is statically linked to run on
+// the host whatever its C library.
+var (
+	agentBinary     *os.File
+	agentBinaryErr  error
+	agentBinaryOnce sync.Once
+)
+
+// newSFTPCmd creates the command that serves the SFTP subsystem. Like the
+// shell, it enters the host namespaces through nsenter with the credentials
+// and groups of the user, and then executes the agent binary through the
+// inherited file descriptor.
+func newSFTPCmd(u *osauth.User, host string) (*exec.Cmd, error) {
+	agentBinaryOnce.Do(func() {
+		agentBinary, agentBinaryErr = os.Open("/proc/self/exe")
+	})
+
+	if agentBinaryErr != nil {
+		return nil, agentBinaryErr
+	}
+
+	// The first extra file is the file descriptor 3 of the child process.
+	nscommand, err := nsenterCommandWrapper(u.UID, u.GID, fmt.Sprintf("/host/%s", u.HomeDir), "/proc/self/fd/3", "sftp")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(nscommand[0], nscommand[1:]...) //nolint:gosec
+	cmd.ExtraFiles = []*os.File{agentBinary}
+	cmd.Env = []string{
+		"HOME=" + u.HomeDir,
+		"USER=" + u.Username,
+		"LOGNAME=" + u.Username,
+		"SHELLHUB_HOST=" + host,
+	}
+
+	return cmd, nil
 }
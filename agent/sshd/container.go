@@ -0,0 +1,82 @@
+package sshd
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"os/user"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
+)
+
+var (
+	ErrContainerNotAllowed     = errors.New("user is not allowed to access the containers")
+	ErrContainerNotRunning     = errors.New("container is not running")
+	ErrContainerNotSupported   = errors.New("containers are only supported by the docker agent")
+	ErrContainerSFTPNotAllowed = errors.New("SFTP is not supported in containers")
+)
+
+// containerGroup is the group whose members are allowed to open sessions in
+// the containers, besides root.
+const containerGroup = "docker"
+
+const containerContextKey = "container"
+
+// sessionContainer returns the container the session is addressed to, or an
+// empty string if it is addressed to the device.
+func sessionContainer(ctx context.Context) string {
+	container, _ := ctx.Value(containerContextKey).(string)
+
+	return container
+}
+
+// newSessionCmd creates the command of the session, which runs inside the
+// container when the session is addressed to one.
+func (s *Server) newSessionCmd(session sshserver.Session, term string) (*exec.Cmd, error) {
+	container := sessionContainer(session.Context())
+	if container == "" {
+		return newShellCmd(s, session.User(), term, session.RawCommand()), nil
+	}
+
+	// Accessing the containers is as good as being root on the device, so
+	// it is restricted to the users already allowed to manage them.
+	if !containerAllowed(osauth.LookupUser(session.User())) {
+		return nil, ErrContainerNotAllowed
+	}
+
+	return newContainerCmd(container, term, s.deviceName, session.RawCommand())
+}
+
+func containerAllowed(u *osauth.User) bool {
+	if u == nil {
+		return false
+	}
+
+	if u.UID == 0 {
+		return true
+	}
+
+	group, err := user.LookupGroup(containerGroup)
+	if err != nil {
+		return false
+	}
+
+	usr, err := user.Lookup(u.Username)
+	if err != nil {
+		return false
+	}
+
+	groups, err := usr.GroupIds()
+	if err != nil {
+		return false
+	}
+
+	for _, gid := range groups {
+		if gid == group.Gid {
+			return true
+		}
+	}
+
+	return false
+}
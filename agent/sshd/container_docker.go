@@ -0,0 +1,127 @@
+// +build docker
+
+package sshd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+// containerShell starts the login shell of the container, which is bash if
+// available in the image.
+const containerShell = "command -v bash >/dev/null && exec bash -l || exec sh -l"
+
+// ListContainers returns the running containers of the device.
+func ListContainers() ([]models.DeviceContainer, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	list, err := cli.ContainerList(context.Background(), types.ContainerListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]models.DeviceContainer, 0, len(list))
+	for _, c := range list {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		containers = append(containers, models.DeviceContainer{
+			ID:     c.ID[:12],
+			Name:   name,
+			Image:  c.Image,
+			Status: c.Status,
+		})
+	}
+
+	return containers, nil
+}
+
+// newContainerCmd creates the command that runs inside the namespaces of the
+// container as its default user, in the same way as docker exec.
+func newContainerCmd(container, term, host, command string) (*exec.Cmd, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	info, err := cli.ContainerInspect(context.Background(), container)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.State == nil || !info.State.Running {
+		return nil, ErrContainerNotRunning
+	}
+
+	uid, gid, err := containerUser(info.State.Pid, info.Config.User)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"/usr/bin/nsenter", "-t", strconv.Itoa(info.State.Pid)}, namespaceArgs(info.State.Pid)...)
+	args = append(args, "-r", "-w", "-S", uid, "-G", gid, "/bin/sh", "-c")
+
+	if command == "" {
+		args = append(args, containerShell)
+	} else {
+		args = append(args, command)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Env = append(info.Config.Env, "TERM="+term, "SHELLHUB_HOST="+host)
+
+	return cmd, nil
+}
+
+// containerUser resolves the user of the container (e.g. nginx, 101 or
+// 101:101) to its UID and GID, looking up the names in the passwd file of
+// the container.
+func containerUser(pid int, spec string) (string, string, error) {
+	if spec == "" {
+		return "0", "0", nil
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+
+	uid, gid := parts[0], ""
+	if len(parts) == 2 {
+		gid = parts[1]
+	}
+
+	if _, err := strconv.Atoi(uid); err != nil {
+		u, err := osauth.LookupUserInFile(fmt.Sprintf("/proc/%d/root/etc/passwd", pid), uid)
+		if err != nil {
+			return "", "", err
+		}
+
+		uid = strconv.Itoa(int(u.UID))
+		if gid == "" {
+			gid = strconv.Itoa(int(u.GID))
+		}
+	}
+
+	if gid == "" {
+		gid = "0"
+	}
+
+	if _, err := strconv.Atoi(gid); err != nil {
+		return "", "", fmt.Errorf("unsupported container group %s", gid)
+	}
+
+	return uid, gid, nil
+}
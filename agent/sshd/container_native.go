@@ -0,0 +1,19 @@
+// +build !docker
+
+package sshd
+
+import (
+	"os/exec"
+
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+// ListContainers returns no containers since they are only reachable by the
+// docker agent.
+func ListContainers() ([]models.DeviceContainer, error) {
+	return nil, nil
+}
+
+func newContainerCmd(_, _, _, _ string) (*exec.Cmd, error) {
+	return nil, ErrContainerNotSupported
+}
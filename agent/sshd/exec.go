@@ -0,0 +1,84 @@
+package sshd
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/shellhub-io/shellhub/agent/pkg/osauth"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+var ErrUnknownUser = errors.New("unknown user")
+
+// limitedBuffer keeps up to limit bytes written to it and discards the rest,
+// so a command with a large output does not exhaust the agent memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Exec runs the command of a job as the user, without a session, and returns
+// its output. The command and its children are killed once the timeout
+// expires.
+func (s *Server) Exec(username, command string, timeout time.Duration) (*models.JobOutput, error) {
+	if osauth.LookupUser(username) == nil {
+		return nil, ErrUnknownUser
+	}
+
+	cmd := newShellCmd(s, username, "", command)
+
+	stdout := &limitedBuffer{limit: models.JobOutputLimit}
+	stderr := &limitedBuffer{limit: models.JobOutputLimit}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Setpgid = true
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) // nolint:errcheck
+	})
+	defer timer.Stop()
+
+	// A command exiting with an error is not a failure of the job, which
+	// reports it through the exit code.
+	cmd.Wait() // nolint:errcheck
+
+	out := &models.JobOutput{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		out.ExitCode = 128 + int(status.Signal())
+
+		if name, ok := signals[status.Signal()]; ok {
+			out.Signal = string(name)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package sshd
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+// ptyDrainTimeout is how long a pty session waits for the remaining output
+// after the command exits.
+const ptyDrainTimeout = time.Second
+
+// signals maps the signals to their names as specified in RFC4254, Section 6.10.
+var signals = map[syscall.Signal]sshserver.Signal{
+	syscall.SIGABRT: sshserver.SIGABRT,
+	syscall.SIGALRM: sshserver.SIGALRM,
+	syscall.SIGFPE:  sshserver.SIGFPE,
+	syscall.SIGHUP:  sshserver.SIGHUP,
+	syscall.SIGILL:  sshserver.SIGILL,
+	syscall.SIGINT:  sshserver.SIGINT,
+	syscall.SIGKILL: sshserver.SIGKILL,
+	syscall.SIGPIPE: sshserver.SIGPIPE,
+	syscall.SIGQUIT: sshserver.SIGQUIT,
+	syscall.SIGSEGV: sshserver.SIGSEGV,
+	syscall.SIGTERM: sshserver.SIGTERM,
+	syscall.SIGUSR1: sshserver.SIGUSR1,
+	syscall.SIGUSR2: sshserver.SIGUSR2,
+}
+
+// exit-signal request payload as specified in RFC4254, Section 6.10
+type exitSignalMsg struct {
+	Signal     string
+	CoreDumped bool
+	Error      string
+	Lang       string
+}
+
+// exitSession reports how the command of the session exited and closes it.
+// A command killed by a signal is reported through exit-signal, unless the
+// signal has no name in the protocol, in which case the exit status is 128
+// plus the signal number as shells do.
+func exitSession(session sshserver.Session, state *os.ProcessState) {
+	if state == nil {
+		session.Exit(255) // nolint:errcheck
+
+		return
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		session.Exit(state.ExitCode()) // nolint:errcheck
+
+		return
+	}
+
+	name, ok := signals[status.Signal()]
+	if !ok {
+		session.Exit(128 + int(status.Signal())) // nolint:errcheck
+
+		return
+	}
+
+	msg := exitSignalMsg{
+		Signal:     string(name),
+		CoreDumped: status.CoreDump(),
+	}
+
+	session.SendRequest("exit-signal", false, ssh.Marshal(&msg)) // nolint:errcheck
+	session.Close()
+}
@@ -0,0 +1,41 @@
+package sshd
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var ErrPtyRestricted = errors.New("terminal is not permitted for this connection")
+
+const restrictionsContextKey = "restrictions"
+
+// gatewayConn is a connection made by the gateway whose sessions are opened
+// inside a container of the device, if any, instead of the device itself, and
+// are restricted as the gateway tells.
+type gatewayConn struct {
+	net.Conn
+	container    string
+	restrictions []string
+}
+
+// HandleGatewayConn handles a connection whose sessions are opened inside the
+// container, which can be referenced either by its name or its ID, or the
+// device if empty. The sessions are denied the features restricted by the
+// gateway (see models.SessionRestrictPty and the like).
+func (s *Server) HandleGatewayConn(conn net.Conn, container string, restrictions []string) {
+	s.sshd.HandleConn(&gatewayConn{Conn: conn, container: container, restrictions: restrictions})
+}
+
+// restricted reports whether the gateway restricted the connection of the
+// context from the feature.
+func restricted(ctx context.Context, restriction string) bool {
+	restrictions, _ := ctx.Value(restrictionsContextKey).([]string)
+	for _, r := range restrictions {
+		if r == restriction {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,295 @@
+package sshd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/sirupsen/logrus"
+)
+
+// persistentSessionEnv is set by the server on the pty sessions that must be
+// kept alive when the user disconnects. Its value is the token of the session,
+// prefixed by the session identifier, used to reattach to it later.
+const persistentSessionEnv = "SHELLHUB_PERSISTENT_SESSION"
+
+// persistentScrollback is the amount of output kept to be replayed when the
+// user reattaches to a persistent session.
+const persistentScrollback = 64 * 1024
+
+// DefaultPersistentSessionTimeout is the time a detached persistent session is
+// kept alive waiting for the user to reattach.
+const DefaultPersistentSessionTimeout = 10 * time.Minute
+
+var ErrPersistentSessionUser = errors.New("persistent session belongs to another user")
+
+// persistentSession is a pty session which outlives the connection of the
+// user, keeping the output written while detached in a scrollback buffer.
+type persistentSession struct {
+	id   string
+	user string
+	cmd  *exec.Cmd
+	pty  *os.File
+
+	mu         sync.Mutex
+	scrollback []byte
+	attached   sshserver.Session
+	replaced   chan struct{}
+	expire     *time.Timer
+
+	// output is closed once all the output of the pty is read and done once
+	// the command has also exited.
+	output chan struct{}
+	done   chan struct{}
+}
+
+// SetPersistentSessionTimeout changes the time a detached persistent session
+// is kept alive.
+func (s *Server) SetPersistentSessionTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.persistentTimeout = timeout
+}
+
+// persistentToken returns the token of the persistent session requested by
+// the server, if any.
+func persistentToken(session sshserver.Session) string {
+	for _, env := range session.Environ() {
+		if strings.HasPrefix(env, persistentSessionEnv+"=") {
+			return strings.TrimPrefix(env, persistentSessionEnv+"=")
+		}
+	}
+
+	return ""
+}
+
+// persistentSessionHandler attaches the pty session to the persistent session
+// of the token, which is started if it does not exist (or has expired).
+func (s *Server) persistentSessionHandler(session sshserver.Session, token, term string, winCh <-chan sshserver.Window) {
+	log := logrus.WithFields(logrus.Fields{
+		"user": session.User(),
+	})
+
+	ps, err := s.persistentSession(session, token, term)
+	if err != nil {
+		log.Warn(err)
+		io.WriteString(session.Stderr(), err.Error()+"\n") // nolint:errcheck
+		session.Exit(255)                                  // nolint:errcheck
+
+		return
+	}
+
+	log = log.WithFields(logrus.Fields{
+		"session": ps.id,
+	})
+
+	log.Info("Persistent session attached")
+
+	replaced := ps.attach(session)
+
+	go func() {
+		for win := range winCh {
+			_ = pty.Setsize(ps.pty, &pty.Winsize{Rows: uint16(win.Height), Cols: uint16(win.Width)})
+		}
+	}()
+
+	go func() {
+		// The input is copied until the user disconnects, the pty is
+		// only closed when the command exits.
+		io.Copy(ps.pty, session) // nolint:errcheck
+	}()
+
+	select {
+	case <-replaced:
+		log.Info("Persistent session attached by another connection")
+	case <-ps.done:
+		log.Info("Persistent session ended")
+
+		exitSession(session, ps.cmd.ProcessState)
+	case <-session.Context().Done():
+		s.mu.Lock()
+		timeout := s.persistentTimeout
+		s.mu.Unlock()
+
+		if ps.detach(session, timeout) {
+			log.WithFields(logrus.Fields{
+				"timeout": timeout,
+			}).Info("Persistent session detached")
+		}
+	}
+}
+
+// persistentSession returns the persistent session of the token, starting a
+// new one if it does not exist.
+func (s *Server) persistentSession(session sshserver.Session, token, term string) (*persistentSession, error) {
+	s.persistentMu.Lock()
+	defer s.persistentMu.Unlock()
+
+	if ps, ok := s.persistent[token]; ok {
+		if ps.user != session.User() {
+			return nil, ErrPersistentSessionUser
+		}
+
+		return ps, nil
+	}
+
+	cmd, err := s.newSessionCmd(session, term)
+	if err != nil {
+		return nil, err
+	}
+
+	f, tty, err := openPty(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &persistentSession{
+		id:     strings.SplitN(token, ".", 2)[0],
+		user:   session.User(),
+		cmd:    cmd,
+		pty:    f,
+		output: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	ut := utmpStartSession(tty.Name(), session.User(), session.RemoteAddr().String())
+
+	go ps.read()
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			logrus.Warn(err)
+		}
+
+		<-ps.output
+
+		utmpEndSession(ut)
+
+		s.persistentMu.Lock()
+		delete(s.persistent, token)
+		s.persistentMu.Unlock()
+
+		ps.mu.Lock()
+		if ps.expire != nil {
+			ps.expire.Stop()
+		}
+		ps.mu.Unlock()
+
+		f.Close()
+
+		close(ps.done)
+	}()
+
+	s.persistent[token] = ps
+
+	return ps, nil
+}
+
+// persistentSessions returns the identifiers of the persistent sessions,
+// including the detached ones.
+func (s *Server) persistentSessions() []string {
+	s.persistentMu.Lock()
+	defer s.persistentMu.Unlock()
+
+	ids := make([]string, 0, len(s.persistent))
+	for _, ps := range s.persistent {
+		ids = append(ids, ps.id)
+	}
+
+	return ids
+}
+
+// read copies the output of the pty to the attached session, keeping the
+// last part of it in the scrollback buffer.
+func (ps *persistentSession) read() {
+	defer close(ps.output)
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := ps.pty.Read(buf)
+		if n > 0 {
+			ps.mu.Lock()
+
+			ps.scrollback = append(ps.scrollback, buf[:n]...)
+			if len(ps.scrollback) > persistentScrollback {
+				ps.scrollback = ps.scrollback[len(ps.scrollback)-persistentScrollback:]
+			}
+
+			if ps.attached != nil {
+				ps.attached.Write(buf[:n]) // nolint:errcheck
+			}
+
+			ps.mu.Unlock()
+		}
+
+		if err != nil {
+			// Reading from the pty fails with EIO once the other side
+			// has been closed by all processes.
+			if !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+				logrus.Warn(err)
+			}
+
+			return
+		}
+	}
+}
+
+// attach replays the scrollback to the session and starts copying the output
+// to it. A session still attached is closed, since only one user can be
+// attached at a time, and the returned channel is closed when it happens to
+// this session.
+func (ps *persistentSession) attach(session sshserver.Session) <-chan struct{} {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.expire != nil {
+		ps.expire.Stop()
+		ps.expire = nil
+	}
+
+	if ps.attached != nil {
+		close(ps.replaced)
+		ps.attached.Close()
+	}
+
+	session.Write(ps.scrollback) // nolint:errcheck
+
+	ps.attached = session
+	ps.replaced = make(chan struct{})
+
+	return ps.replaced
+}
+
+// detach stops copying the output to the session, killing the command if no
+// session attaches again within the timeout. It returns false if another
+// session is already attached.
+func (ps *persistentSession) detach(session sshserver.Session, timeout time.Duration) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.attached != session {
+		return false
+	}
+
+	ps.attached = nil
+	ps.expire = time.AfterFunc(timeout, func() {
+		logrus.WithFields(logrus.Fields{
+			"session": ps.id,
+		}).Info("Persistent session expired")
+
+		// The whole process group is killed, so the processes started by
+		// the shell do not outlive it.
+		syscall.Kill(-ps.cmd.Process.Pid, syscall.SIGHUP) // nolint:errcheck
+	})
+
+	return true
+}
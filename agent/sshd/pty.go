@@ -1,6 +1,7 @@
 package sshd
 
 import (
+	"errors"
 	"io"
 	"os"
 	"os/exec"
@@ -44,10 +45,13 @@ func openPty(c *exec.Cmd) (*os.File, *os.File, error) {
 	return ptmx, tty, err
 }
 
-func startPty(c *exec.Cmd, out io.ReadWriter, winCh <-chan ssh.Window) (*os.File, error) {
+// startPty starts the command attached to a new pty whose input and output
+// are copied from and to out. The returned channel is closed when all the
+// output of the pty has been copied.
+func startPty(c *exec.Cmd, out io.ReadWriter, winCh <-chan ssh.Window) (*os.File, <-chan struct{}, error) {
 	f, tty, err := openPty(c)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	go func() {
@@ -56,9 +60,15 @@ func startPty(c *exec.Cmd, out io.ReadWriter, winCh <-chan ssh.Window) (*os.File
 		}
 	}()
 
+	done := make(chan struct{})
+
 	go func() {
+		defer close(done)
+
+		// Reading from the pty fails with EIO once the other side has been
+		// closed by all processes, which marks the end of the output.
 		_, err := io.Copy(out, f)
-		if err != nil {
+		if err != nil && !errors.Is(err, syscall.EIO) {
 			logrus.Warn(err)
 		}
 	}()
@@ -70,5 +80,5 @@ func startPty(c *exec.Cmd, out io.ReadWriter, winCh <-chan ssh.Window) (*os.File
 		}
 	}()
 
-	return tty, nil
+	return tty, done, nil
 }
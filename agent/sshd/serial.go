@@ -0,0 +1,265 @@
+package sshd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// serialUserPrefix is the prefix of the login user of the sessions attached
+// to a serial port of the device (e.g. serial:ttyUSB0).
+const serialUserPrefix = "serial:"
+
+// DefaultSerialUser is the OS user whose credentials authenticate the logins
+// to the serial ports.
+const DefaultSerialUser = "root"
+
+var (
+	ErrInvalidSerialPort  = errors.New("invalid serial port")
+	ErrSerialPortNotFound = errors.New("serial port is not available")
+	ErrSerialPortBusy     = errors.New("serial port is in use by another session")
+)
+
+// serialBaudRates maps the supported baud rates to their termios speeds.
+var serialBaudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+// SerialPort is a serial port of the device exposed as a SSH target.
+type SerialPort struct {
+	Name     string
+	Baud     int
+	DataBits int
+	Parity   byte
+	StopBits int
+}
+
+// ParseSerialPort parses the configuration of a serial port in the form
+// name[:baud[:mode]], where the mode is the number of data bits, the parity
+// (N, E or O) and the number of stop bits (e.g. ttyUSB0:9600:7E1). The
+// default baud rate is 115200 and the default mode is 8N1.
+func ParseSerialPort(spec string) (*SerialPort, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 || parts[0] == "" || strings.Contains(parts[0], "/") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSerialPort, spec)
+	}
+
+	port := &SerialPort{
+		Name:     parts[0],
+		Baud:     115200,
+		DataBits: 8,
+		Parity:   'N',
+		StopBits: 1,
+	}
+
+	if len(parts) > 1 {
+		baud, err := strconv.Atoi(parts[1])
+		if _, ok := serialBaudRates[baud]; err != nil || !ok {
+			return nil, fmt.Errorf("%w: unsupported baud rate %s", ErrInvalidSerialPort, parts[1])
+		}
+
+		port.Baud = baud
+	}
+
+	if len(parts) > 2 {
+		mode := strings.ToUpper(parts[2])
+		if len(mode) != 3 || mode[0] < '5' || mode[0] > '8' || !strings.ContainsRune("NEO", rune(mode[1])) || (mode[2] != '1' && mode[2] != '2') {
+			return nil, fmt.Errorf("%w: unsupported mode %s", ErrInvalidSerialPort, parts[2])
+		}
+
+		port.DataBits = int(mode[0] - '0')
+		port.Parity = mode[1]
+		port.StopBits = int(mode[2] - '0')
+	}
+
+	return port, nil
+}
+
+// SetSerialPorts changes the serial ports exposed as SSH targets and the OS
+// user authenticating the logins to them.
+func (s *Server) SetSerialPorts(ports []*SerialPort, user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.serialPorts = make(map[string]*SerialPort, len(ports))
+	for _, port := range ports {
+		s.serialPorts[port.Name] = port
+	}
+
+	s.serialUser = user
+}
+
+// loginUser returns the OS user authenticating the login of user, which is
+// the serial user for the logins to the serial ports.
+func (s *Server) loginUser(user string) string {
+	if !strings.HasPrefix(user, serialUserPrefix) {
+		return user
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.serialUser
+}
+
+// serialHandler attaches the session to the serial port until either of them
+// is closed. The port is locked, so only one session is attached at a time.
+func (s *Server) serialHandler(session sshserver.Session, name string) {
+	log := logrus.WithFields(logrus.Fields{
+		"user":       session.User(),
+		"port":       name,
+		"remoteaddr": session.RemoteAddr(),
+	})
+
+	s.mu.Lock()
+	port, ok := s.serialPorts[name]
+	s.mu.Unlock()
+
+	if !ok {
+		log.Warn(ErrSerialPortNotFound)
+		io.WriteString(session.Stderr(), ErrSerialPortNotFound.Error()+"\n") // nolint:errcheck
+		session.Exit(255)                                                    // nolint:errcheck
+
+		return
+	}
+
+	f, err := openSerialPort(port)
+	if err != nil {
+		log.Warn(err)
+		io.WriteString(session.Stderr(), err.Error()+"\n") // nolint:errcheck
+		session.Exit(255)                                  // nolint:errcheck
+
+		return
+	}
+
+	var once sync.Once
+	closePort := func() {
+		once.Do(func() {
+			f.Close()
+		})
+	}
+
+	defer closePort()
+
+	log.Info("Serial console attached")
+
+	go func() {
+		io.Copy(f, session) // nolint:errcheck
+
+		// The output is copied until the port is closed, which happens
+		// as soon as the user disconnects.
+		closePort()
+	}()
+
+	io.Copy(session, f) // nolint:errcheck
+
+	log.Info("Serial console detached")
+
+	session.Exit(0) // nolint:errcheck
+}
+
+// openSerialPort opens and configures the serial port in raw mode, holding an
+// exclusive lock on it while it is open.
+func openSerialPort(port *SerialPort) (*os.File, error) {
+	// The port is opened without waiting for the carrier and without
+	// becoming the controlling terminal of the agent.
+	f, err := os.OpenFile(filepath.Join("/dev", port.Name), os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	// The descriptor is not taken with Fd, which would make it blocking and
+	// prevent closing the port while it is being read.
+	var setupErr error
+	if err := rc.Control(func(fd uintptr) {
+		setupErr = setupSerialPort(int(fd), port)
+	}); err != nil {
+		setupErr = err
+	}
+
+	if setupErr != nil {
+		f.Close()
+
+		return nil, setupErr
+	}
+
+	return f, nil
+}
+
+// setupSerialPort locks the port and configures it in raw mode.
+func setupSerialPort(fd int, port *SerialPort) error {
+	if err := unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return ErrSerialPortBusy
+		}
+
+		return err
+	}
+
+	// Prevent the port from being opened by other processes while it is
+	// attached, even those not honoring the lock.
+	if err := unix.IoctlSetInt(fd, unix.TIOCEXCL, 0); err != nil {
+		return err
+	}
+
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CBAUD | unix.CRTSCTS
+	t.Cflag |= unix.CLOCAL | unix.CREAD
+
+	t.Cflag |= map[int]uint32{5: unix.CS5, 6: unix.CS6, 7: unix.CS7, 8: unix.CS8}[port.DataBits]
+
+	switch port.Parity {
+	case 'E':
+		t.Cflag |= unix.PARENB
+	case 'O':
+		t.Cflag |= unix.PARENB | unix.PARODD
+	}
+
+	if port.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+
+	speed := serialBaudRates[port.Baud]
+	t.Cflag |= speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
@@ -2,15 +2,18 @@ package sshd
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,10 +43,20 @@ type Server struct {
 	authData           *models.DeviceAuthResponse
 	cmds               map[string]*exec.Cmd
 	Sessions           map[string]net.Conn
+	sessionsMu         sync.Mutex
 	deviceName         string
 	mu                 sync.Mutex
 	keepAliveInterval  int
 	singleUserPassword string
+
+	// persistent holds the persistent sessions by token.
+	persistent        map[string]*persistentSession
+	persistentMu      sync.Mutex
+	persistentTimeout time.Duration
+
+	// serialPorts holds the serial ports exposed as SSH targets by name.
+	serialPorts map[string]*SerialPort
+	serialUser  string
 }
 
 func NewServer(api client.Client, authData *models.DeviceAuthResponse, privateKey string, keepAliveInterval int, singleUserPassword string) *Server {
@@ -53,14 +66,31 @@ func NewServer(api client.Client, authData *models.DeviceAuthResponse, privateKe
 		cmds:              make(map[string]*exec.Cmd),
 		Sessions:          make(map[string]net.Conn),
 		keepAliveInterval: keepAliveInterval,
+		persistent:        make(map[string]*persistentSession),
+		persistentTimeout: DefaultPersistentSessionTimeout,
+		serialPorts:       make(map[string]*SerialPort),
+		serialUser:        DefaultSerialUser,
 	}
 
+	forwardHandler := &sshserver.ForwardedTCPHandler{}
+
 	s.sshd = &sshserver.Server{
 		PasswordHandler:  s.passwordHandler,
 		PublicKeyHandler: s.publicKeyHandler,
 		Handler:          s.sessionHandler,
-		RequestHandlers:  sshserver.DefaultRequestHandlers,
-		ChannelHandlers:  sshserver.DefaultChannelHandlers,
+		RequestHandlers: map[string]sshserver.RequestHandler{
+			"tcpip-forward":        forwardHandler.HandleSSHRequest,
+			"cancel-tcpip-forward": forwardHandler.HandleSSHRequest,
+		},
+		ChannelHandlers: map[string]sshserver.ChannelHandler{
+			"session":      sshserver.DefaultSessionHandler,
+			"direct-tcpip": sshserver.DirectTCPIPHandler,
+		},
+		LocalPortForwardingCallback:   s.localPortForwardingCallback,
+		ReversePortForwardingCallback: s.reversePortForwardingCallback,
+		SubsystemHandlers: map[string]sshserver.SubsystemHandler{
+			"sftp": s.sftpSubsystemHandler,
+		},
 		ConnCallback: func(ctx sshserver.Context, conn net.Conn) net.Conn {
 			closeCallback := func(id string) {
 				s.mu.Lock()
@@ -72,6 +102,11 @@ func NewServer(api client.Client, authData *models.DeviceAuthResponse, privateKe
 				}
 			}
 
+			if c, ok := conn.(*gatewayConn); ok {
+				ctx.SetValue(containerContextKey, c.container)
+				ctx.SetValue(restrictionsContextKey, c.restrictions)
+			}
+
 			return &sshConn{conn, closeCallback, ctx}
 		},
 	}
@@ -84,6 +119,29 @@ func NewServer(api client.Client, authData *models.DeviceAuthResponse, privateKe
 	return s
 }
 
+// The gateway is responsible for checking whether port forwarding is allowed
+// in the device namespace, so every forwarding that reaches the agent is
+// accepted, unless the gateway restricted the connection spliced to the agent.
+func (s *Server) localPortForwardingCallback(ctx sshserver.Context, host string, port uint32) bool {
+	logrus.WithFields(logrus.Fields{
+		"user": ctx.User(),
+		"host": host,
+		"port": port,
+	}).Info("Local port forwarding requested")
+
+	return !restricted(ctx, models.SessionRestrictPortForwarding)
+}
+
+func (s *Server) reversePortForwardingCallback(ctx sshserver.Context, host string, port uint32) bool {
+	logrus.WithFields(logrus.Fields{
+		"user": ctx.User(),
+		"host": host,
+		"port": port,
+	}).Info("Remote port forwarding requested")
+
+	return !restricted(ctx, models.SessionRestrictPortForwarding)
+}
+
 func (s *Server) ListenAndServe() error {
 	return s.sshd.ListenAndServe()
 }
@@ -96,6 +154,24 @@ func (s *Server) SetDeviceName(name string) {
 	s.deviceName = name
 }
 
+// SetAuthData replaces the device authorization used to authenticate the
+// public keys, after the device is authorized again.
+func (s *Server) SetAuthData(authData *models.DeviceAuthResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.authData = authData
+}
+
+// SetKeepAliveInterval changes the interval of the keep alive messages sent to
+// the sessions started from now on.
+func (s *Server) SetKeepAliveInterval(interval int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keepAliveInterval = interval
+}
+
 func (s *Server) sessionHandler(session sshserver.Session) {
 	sspty, winCh, isPty := session.Pty()
 
@@ -106,18 +182,59 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 
 	log.Info("New session request")
 
-	go StartKeepAliveLoop(time.Second*time.Duration(s.keepAliveInterval), session)
+	s.mu.Lock()
+	keepAliveInterval := s.keepAliveInterval
+	s.mu.Unlock()
+
+	go StartKeepAliveLoop(time.Second*time.Duration(keepAliveInterval), session)
+
+	if isPty && restricted(session.Context(), models.SessionRestrictPty) {
+		log.Warn(ErrPtyRestricted)
+		io.WriteString(session.Stderr(), ErrPtyRestricted.Error()+"\n") // nolint:errcheck
+		session.Exit(255)                                               // nolint:errcheck
+
+		return
+	}
+
+	if strings.HasPrefix(session.User(), serialUserPrefix) {
+		s.serialHandler(session, strings.TrimPrefix(session.User(), serialUserPrefix))
+
+		return
+	}
 
 	if isPty { //nolint:nestif
-		scmd := newShellCmd(s, session.User(), sspty.Term)
+		if sspty.Term == "" {
+			sspty.Term = "xterm"
+		}
+
+		if token := persistentToken(session); token != "" {
+			s.persistentSessionHandler(session, token, sspty.Term, winCh)
+
+			return
+		}
 
-		pts, err := startPty(scmd, session, winCh)
+		scmd, err := s.newSessionCmd(session, sspty.Term)
 		if err != nil {
-			logrus.Warn(err)
+			log.Warn(err)
+			io.WriteString(session.Stderr(), err.Error()+"\n") // nolint:errcheck
+			session.Exit(255)                                  // nolint:errcheck
+
+			return
 		}
 
 		u := osauth.LookupUser(session.User())
 
+		stopAgentForwarding := setupAgentForwarding(session, u, scmd)
+		defer stopAgentForwarding()
+
+		pts, outputDone, err := startPty(scmd, session, winCh)
+		if err != nil {
+			logrus.Warn(err)
+			session.Exit(255) // nolint:errcheck
+
+			return
+		}
+
 		err = os.Chown(pts.Name(), int(u.UID), -1)
 		if err != nil {
 			logrus.Warn(err)
@@ -126,10 +243,11 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 		remoteAddr := session.RemoteAddr()
 
 		logrus.WithFields(logrus.Fields{
-			"user":       session.User(),
-			"pty":        pts.Name(),
-			"remoteaddr": remoteAddr,
-			"localaddr":  session.LocalAddr(),
+			"user":        session.User(),
+			"pty":         pts.Name(),
+			"remoteaddr":  remoteAddr,
+			"localaddr":   session.LocalAddr(),
+			"Raw command": session.RawCommand(),
 		}).Info("Session started")
 
 		ut := utmpStartSession(
@@ -146,6 +264,13 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 			logrus.Warn(err)
 		}
 
+		// The output left in the pty must reach the user before the exit
+		// status, unless it is kept open by a process left in background.
+		select {
+		case <-outputDone:
+		case <-time.After(ptyDrainTimeout):
+		}
+
 		logrus.WithFields(logrus.Fields{
 			"user":       session.User(),
 			"pty":        pts.Name(),
@@ -154,11 +279,27 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 		}).Info("Session ended")
 
 		utmpEndSession(ut)
+
+		exitSession(session, scmd.ProcessState)
 	} else {
 		u := osauth.LookupUser(session.User())
-		cmd := newCmd(u, "", "", s.deviceName, session.Command()...)
 
-		stdout, _ := cmd.StdoutPipe()
+		cmd, err := s.newSessionCmd(session, "")
+		if err != nil {
+			log.Warn(err)
+			io.WriteString(session.Stderr(), err.Error()+"\n") // nolint:errcheck
+			session.Exit(255)                                  // nolint:errcheck
+
+			return
+		}
+
+		stopAgentForwarding := setupAgentForwarding(session, u, cmd)
+		defer stopAgentForwarding()
+
+		// The command output is copied to the session by the exec package,
+		// so Wait returns only after all of it has been sent to the user.
+		cmd.Stdout = session
+		cmd.Stderr = session.Stderr()
 		stdin, _ := cmd.StdinPipe()
 
 		logrus.WithFields(logrus.Fields{
@@ -168,25 +309,26 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 			"Raw command": session.RawCommand(),
 		}).Info("Command started")
 
-		err := cmd.Start()
-		if err != nil {
+		if err := cmd.Start(); err != nil {
 			logrus.Warn(err)
+			session.Exit(255) // nolint:errcheck
+
+			return
 		}
 
+		s.mu.Lock()
+		s.cmds[session.Context().Value(sshserver.ContextKeySessionID).(string)] = cmd
+		s.mu.Unlock()
+
 		go func() {
 			if _, err := io.Copy(stdin, session); err != nil {
-				fmt.Println(err) //nolint:forbidigo
+				logrus.Warn(err)
 			}
-		}()
 
-		go func() {
-			if _, err := io.Copy(session, stdout); err != nil {
-				fmt.Println(err) //nolint:forbidigo
-			}
+			stdin.Close()
 		}()
 
-		err = cmd.Wait()
-		if err != nil {
+		if err := cmd.Wait(); err != nil {
 			logrus.Warn(err)
 		}
 
@@ -196,7 +338,79 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 			"localaddr":   session.LocalAddr(),
 			"Raw command": session.RawCommand(),
 		}).Info("Command ended")
+
+		exitSession(session, cmd.ProcessState)
+	}
+}
+
+func (s *Server) sftpSubsystemHandler(session sshserver.Session) {
+	log := logrus.WithFields(logrus.Fields{
+		"user":       session.User(),
+		"remoteaddr": session.RemoteAddr(),
+		"localaddr":  session.LocalAddr(),
+	})
+
+	log.Info("New SFTP session request")
+
+	if sessionContainer(session.Context()) != "" {
+		log.Warn(ErrContainerSFTPNotAllowed)
+		session.Exit(1) // nolint:errcheck
+
+		return
+	}
+
+	u := osauth.LookupUser(session.User())
+	if u == nil {
+		session.Exit(1) // nolint:errcheck
+
+		return
+	}
+
+	cmd, err := newSFTPCmd(u, s.deviceName)
+	if err != nil {
+		log.Warn(err)
+		session.Exit(1) // nolint:errcheck
+
+		return
+	}
+
+	cmd.Stderr = session.Stderr()
+
+	stdout, _ := cmd.StdoutPipe()
+	stdin, _ := cmd.StdinPipe()
+
+	if err := cmd.Start(); err != nil {
+		log.Warn(err)
+		session.Exit(1) // nolint:errcheck
+
+		return
+	}
+
+	s.mu.Lock()
+	s.cmds[session.Context().Value(sshserver.ContextKeySessionID).(string)] = cmd
+	s.mu.Unlock()
+
+	log.Info("SFTP session started")
+
+	go func() {
+		if _, err := io.Copy(stdin, session); err != nil {
+			log.Warn(err)
+		}
+
+		stdin.Close()
+	}()
+
+	if _, err := io.Copy(session, stdout); err != nil {
+		log.Warn(err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		log.Warn(err)
 	}
+
+	log.Info("SFTP session ended")
+
+	session.Exit(cmd.ProcessState.ExitCode()) // nolint:errcheck
 }
 
 func (s *Server) passwordHandler(ctx sshserver.Context, pass string) bool {
@@ -206,7 +420,7 @@ func (s *Server) passwordHandler(ctx sshserver.Context, pass string) bool {
 	var ok bool
 
 	if s.singleUserPassword == "" {
-		ok = osauth.AuthUser(ctx.User(), pass)
+		ok = osauth.AuthUser(s.loginUser(ctx.User()), pass)
 	} else {
 		ok = osauth.VerifyPasswordHash(s.singleUserPassword, pass)
 	}
@@ -221,7 +435,7 @@ func (s *Server) passwordHandler(ctx sshserver.Context, pass string) bool {
 }
 
 func (s *Server) publicKeyHandler(ctx sshserver.Context, key sshserver.PublicKey) bool {
-	if osauth.LookupUser(ctx.User()) == nil {
+	if osauth.LookupUser(s.loginUser(ctx.User())) == nil {
 		return false
 	}
 
@@ -242,10 +456,14 @@ func (s *Server) publicKeyHandler(ctx sshserver.Context, key sshserver.PublicKey
 
 	sigHash := sha256.Sum256(sigBytes)
 
+	s.mu.Lock()
+	token := s.authData.Token
+	s.mu.Unlock()
+
 	res, err := s.api.AuthPublicKey(&models.PublicKeyAuthRequest{
-		Fingerprint: ssh.FingerprintLegacyMD5(key),
+		Fingerprint: ssh.FingerprintSHA256(key),
 		Data:        string(sigBytes),
-	}, s.authData.Token)
+	}, token)
 	if err != nil {
 		return false
 	}
@@ -255,25 +473,70 @@ func (s *Server) publicKeyHandler(ctx sshserver.Context, key sshserver.PublicKey
 		return false
 	}
 
-	cryptoKey := key.(ssh.CryptoPublicKey)
-	pubCrypto := cryptoKey.CryptoPublicKey()
-	pubKey := pubCrypto.(*rsa.PublicKey)
-
-	if err = rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, sigHash[:], digest); err != nil {
+	cryptoKey, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
 		return false
 	}
 
-	return true
+	// RSA and ECDSA signatures are made over the SHA256 hash of the data,
+	// while Ed25519 signatures are made over the data itself.
+	switch pubKey := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, sigHash[:], digest) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pubKey, sigHash[:], digest)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pubKey, sigBytes, digest)
+	}
+
+	return false
+}
+
+// AddSession tracks the connection of a session opened by the server.
+func (s *Server) AddSession(id string, conn net.Conn) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	s.Sessions[id] = conn
+}
+
+// ActiveSessions returns the identifiers of the sessions being served.
+func (s *Server) ActiveSessions() []string {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sessions := make([]string, 0, len(s.Sessions))
+	for id := range s.Sessions {
+		sessions = append(sessions, id)
+	}
+
+	// The detached persistent sessions are still active on the device
+	for _, id := range s.persistentSessions() {
+		if _, ok := s.Sessions[id]; !ok {
+			sessions = append(sessions, id)
+		}
+	}
+
+	sort.Strings(sessions)
+
+	return sessions
 }
 
 func (s *Server) CloseSession(id string) {
-	if session, ok := s.Sessions[id]; ok {
+	s.sessionsMu.Lock()
+	session, ok := s.Sessions[id]
+	delete(s.Sessions, id)
+	s.sessionsMu.Unlock()
+
+	if ok {
 		session.Close()
-		delete(s.Sessions, id)
 	}
 }
 
-func newShellCmd(s *Server, username, term string) *exec.Cmd {
+// newShellCmd creates the command executed by the user's shell for a session.
+// Without a command the shell is started as a login shell, otherwise the
+// command is run by the shell the same way OpenSSH does.
+func newShellCmd(s *Server, username, term, command string) *exec.Cmd {
 	shell := os.Getenv("SHELL")
 
 	u := osauth.LookupUser(username)
@@ -282,11 +545,9 @@ func newShellCmd(s *Server, username, term string) *exec.Cmd {
 		shell = u.Shell
 	}
 
-	if term == "" {
-		term = "xterm"
+	if command == "" {
+		return newCmd(u, shell, term, s.deviceName, shell, "--login")
 	}
 
-	cmd := newCmd(u, shell, term, s.deviceName, shell, "--login")
-
-	return cmd
+	return newCmd(u, shell, term, s.deviceName, shell, "-c", command)
 }
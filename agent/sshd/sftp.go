@@ -0,0 +1,49 @@
+package sshd
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+// sftpPipe joins the process standard input and output in a single
+// io.ReadWriteCloser to be used as the SFTP server transport.
+type sftpPipe struct {
+	in  *os.File
+	out *os.File
+}
+
+func (p *sftpPipe) Read(data []byte) (int, error) {
+	return p.in.Read(data)
+}
+
+func (p *sftpPipe) Write(data []byte) (int, error) {
+	return p.out.Write(data)
+}
+
+func (p *sftpPipe) Close() error {
+	if err := p.in.Close(); err != nil {
+		return err
+	}
+
+	return p.out.Close()
+}
+
+// NewSFTPServer serves the SFTP protocol over the standard input and output.
+//
+// It is meant to run in a child process of the agent started by the SFTP
+// subsystem handler, so the file operations are performed with the
+// credentials of the authenticated user.
+func NewSFTPServer() error {
+	server, err := sftp.NewServer(&sftpPipe{in: os.Stdin, out: os.Stdout})
+	if err != nil {
+		return err
+	}
+
+	if err := server.Serve(); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
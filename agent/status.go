@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultStatusSocket is the unix socket where the agent serves its status
+// to be queried by the status command.
+const DefaultStatusSocket = "/var/run/shellhub-agent.sock"
+
+// Status is the state of a running agent reported by the status command.
+type Status struct {
+	Version       string   `json:"version"`
+	ServerAddress string   `json:"server_address"`
+	Connected     bool     `json:"connected"`
+	UID           string   `json:"uid,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Namespace     string   `json:"namespace,omitempty"`
+	Sessions      []string `json:"sessions"`
+
+	// Health of the connection to the server, the connected time is in
+	// seconds.
+	Reconnects    int    `json:"reconnects"`
+	LastError     string `json:"last_error,omitempty"`
+	ConnectedTime uint64 `json:"connected_time"`
+}
+
+// status returns the current state of the agent.
+func (a *Agent) status(sessions []string) *Status {
+	stats := a.connectionStats()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	status := &Status{
+		Version:       AgentVersion,
+		ServerAddress: a.opts.ServerAddress,
+		Connected:     a.connection.connected,
+		Sessions:      sessions,
+		Reconnects:    stats.Reconnects,
+		LastError:     stats.LastError,
+		ConnectedTime: stats.ConnectedTime,
+	}
+
+	if a.authData != nil {
+		status.UID = a.authData.UID
+		status.Name = a.authData.Name
+		status.Namespace = a.authData.Namespace
+	}
+
+	return status
+}
+
+// serveStatus serves the status returned by fn over the unix socket at path.
+func serveStatus(path string, fn func() *Status) error {
+	// Remove the socket left behind by a previous execution
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	// The status exposes the active sessions, so it is restricted to the
+	// user running the agent.
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+
+		return err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fn()) // nolint:errcheck
+	}).Methods("GET")
+
+	return http.Serve(listener, router) //nolint:gosec
+}
+
+// queryStatus requests the status of the agent serving at the unix socket path.
+func queryStatus(path string) (*Status, error) {
+	cli := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+
+	res, err := cli.Get("http://agent/status") //nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status response: %s", res.Status)
+	}
+
+	status := &Status{}
+	if err := json.NewDecoder(res.Body).Decode(status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minReconnectInterval is the delay before the first attempt to connect
+	// again to the server after the connection is lost.
+	minReconnectInterval = time.Second
+
+	// maxReconnectInterval is the upper bound of the delay between two
+	// attempts to connect to the server.
+	maxReconnectInterval = time.Minute
+)
+
+// connectionState is the health of the reverse connection to the server.
+type connectionState struct {
+	connected   bool
+	established bool
+	connectedAt time.Time
+	reconnects  int
+	lastError   string
+}
+
+// backoff computes the delay between the attempts to connect to the server,
+// which grows exponentially up to the max. The delay is randomized, so devices
+// disconnected at once by a server outage do not reconnect at the same time.
+type backoff struct {
+	min     time.Duration
+	max     time.Duration
+	attempt uint
+	rand    *rand.Rand
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{
+		min:  min,
+		max:  max,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+}
+
+// next returns the delay before the next attempt, which is between the half
+// and the whole of the exponential delay.
+func (b *backoff) next() time.Duration {
+	delay := b.max
+	if b.attempt < 32 {
+		if d := b.min << b.attempt; d > 0 && d < b.max {
+			delay = d
+		}
+	}
+
+	b.attempt++
+
+	return delay/2 + time.Duration(b.rand.Int63n(int64(delay/2)+1))
+}
+
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// supervise keeps the reverse connection to the server, connecting again with
+// an exponential backoff whenever it fails. When the server rejects the token,
+// the device is authorized again and authorized is called.
+func (a *Agent) supervise(tunnel *Tunnel, authorized func()) {
+	b := newBackoff(minReconnectInterval, maxReconnectInterval)
+
+	for {
+		listener, err := a.newReverseListener()
+		if err != nil {
+			a.setConnectionError(err)
+
+			logrus.WithFields(logrus.Fields{"err": err}).Warning("Failed to connect to the server")
+
+			if errors.Is(err, client.ErrUnauthorized) {
+				if err := a.authorize(); err != nil {
+					logrus.WithFields(logrus.Fields{"err": err}).Error("Failed to authorize device")
+				} else {
+					authorized()
+				}
+			}
+
+			time.Sleep(b.next())
+
+			continue
+		}
+
+		b.reset()
+
+		a.setConnected(true)
+
+		a.mu.RLock()
+		namespace := a.authData.Namespace
+		tenantName := a.authData.Name
+		a.mu.RUnlock()
+		sshEndpoint := a.serverInfo.Endpoints.SSH
+
+		sshid := strings.NewReplacer(
+			"{namespace}", namespace,
+			"{tenantName}", tenantName,
+			"{sshEndpoint}", strings.Split(sshEndpoint, ":")[0],
+		).Replace("{namespace}.{tenantName}@{sshEndpoint}")
+
+		logrus.WithFields(logrus.Fields{
+			"namespace":      namespace,
+			"hostname":       tenantName,
+			"server_address": a.config().ServerAddress,
+			"ssh_server":     sshEndpoint,
+			"sshid":          sshid,
+		}).Info("Server connection established")
+
+		err = tunnel.Listen(listener)
+
+		a.setConnected(false)
+
+		if err != nil {
+			a.setConnectionError(err)
+		}
+
+		logrus.WithFields(logrus.Fields{"err": err}).Warning("Server connection lost")
+
+		time.Sleep(b.next())
+	}
+}
+
+// setConnected records whether the reverse connection to the server is up.
+func (a *Agent) setConnected(connected bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if connected {
+		if a.connection.established {
+			a.connection.reconnects++
+		}
+
+		a.connection.established = true
+		a.connection.connectedAt = time.Now()
+	}
+
+	a.connection.connected = connected
+}
+
+// setConnectionError records the last error of the connection to the server.
+func (a *Agent) setConnectionError(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.connection.lastError = err.Error()
+}
+
+// connectionStats returns the health of the connection to the server.
+func (a *Agent) connectionStats() *models.DeviceConnection {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	stats := &models.DeviceConnection{
+		Reconnects: a.connection.reconnects,
+		LastError:  a.connection.lastError,
+	}
+
+	if a.connection.connected {
+		stats.ConnectedTime = uint64(time.Since(a.connection.connectedAt).Seconds())
+	}
+
+	return stats
+}
@@ -14,6 +14,10 @@ type Tunnel struct {
 	srv          *http.Server
 	connHandler  func(w http.ResponseWriter, r *http.Request)
 	closeHandler func(w http.ResponseWriter, r *http.Request)
+	execHandler  func(w http.ResponseWriter, r *http.Request)
+	httpHandler  func(w http.ResponseWriter, r *http.Request)
+
+	diagnosticsHandler func(w http.ResponseWriter, r *http.Request)
 }
 
 func NewTunnel() *Tunnel {
@@ -33,6 +37,15 @@ func NewTunnel() *Tunnel {
 		closeHandler: func(w http.ResponseWriter, r *http.Request) {
 			panic("closeHandler can not be nil")
 		},
+		execHandler: func(w http.ResponseWriter, r *http.Request) {
+			panic("execHandler can not be nil")
+		},
+		httpHandler: func(w http.ResponseWriter, r *http.Request) {
+			panic("httpHandler can not be nil")
+		},
+		diagnosticsHandler: func(w http.ResponseWriter, r *http.Request) {
+			panic("diagnosticsHandler can not be nil")
+		},
 	}
 	t.router.HandleFunc("/ssh/{id}", func(w http.ResponseWriter, r *http.Request) {
 		t.connHandler(w, r)
@@ -40,6 +53,15 @@ func NewTunnel() *Tunnel {
 	t.router.HandleFunc("/ssh/close/{id}", func(w http.ResponseWriter, r *http.Request) {
 		t.closeHandler(w, r)
 	})
+	t.router.HandleFunc("/exec", func(w http.ResponseWriter, r *http.Request) {
+		t.execHandler(w, r)
+	}).Methods("POST")
+	t.router.HandleFunc("/diagnostics", func(w http.ResponseWriter, r *http.Request) {
+		t.diagnosticsHandler(w, r)
+	}).Methods("POST")
+	t.router.PathPrefix("/http/{port:[0-9]+}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.httpHandler(w, r)
+	})
 
 	return t
 }
@@ -5,22 +5,30 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/shellhub-io/shellhub/api/store"
+	storecache "github.com/shellhub-io/shellhub/api/store/cache"
 	"github.com/shellhub-io/shellhub/pkg/models"
 )
 
 type Context struct {
 	store store.Store
+	cache storecache.Cache
 	echo.Context
 }
 
-func NewContext(store store.Store, c echo.Context) *Context {
-	return &Context{store: store, Context: c}
+func NewContext(store store.Store, cache storecache.Cache, c echo.Context) *Context {
+	return &Context{store: store, cache: cache, Context: c}
 }
 
 func (c *Context) Store() store.Store {
 	return c.store
 }
 
+// Cache returns the cache of the state shared by the API instances which is
+// not persisted in the store (e.g. the authentication limits).
+func (c *Context) Cache() storecache.Cache {
+	return c.cache
+}
+
 func (c *Context) Tenant() *models.Tenant {
 	tenant := c.Request().Header.Get("X-Tenant-ID")
 	if tenant != "" {
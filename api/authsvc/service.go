@@ -3,6 +3,8 @@ package authsvc
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -19,12 +21,17 @@ import (
 	"github.com/cnf/structhash"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/clock"
 	"github.com/shellhub-io/shellhub/pkg/models"
+	"golang.org/x/crypto/ssh"
 	"gopkg.in/go-playground/validator.v9"
 )
 
-var ErrUnauthorized = errors.New("unauthorized")
+var (
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
 
 type Service interface {
 	AuthDevice(ctx context.Context, req *models.DeviceAuthRequest) (*models.DeviceAuthResponse, error)
@@ -33,6 +40,7 @@ type Service interface {
 	AuthPublicKey(ctx context.Context, req *models.PublicKeyAuthRequest) (*models.PublicKeyAuthResponse, error)
 	AuthSwapToken(ctx context.Context, ID, tenant string) (*models.UserAuthResponse, error)
 	AuthUserInfo(ctx context.Context, username, tenant, token string) (*models.UserAuthResponse, error)
+	AuthBreakGlassKeys(ctx context.Context, uid models.UID) (*models.BreakGlassKeys, error)
 	PublicKey() *rsa.PublicKey
 }
 
@@ -58,12 +66,14 @@ func (s *service) AuthDevice(ctx context.Context, req *models.DeviceAuthRequest)
 	uid := sha256.Sum256(structhash.Dump(req.DeviceAuth, 1))
 
 	device := models.Device{
-		UID:       hex.EncodeToString(uid[:]),
-		Identity:  req.Identity,
-		Info:      req.Info,
-		PublicKey: req.PublicKey,
-		TenantID:  req.TenantID,
-		LastSeen:  clock.Now(),
+		UID:        hex.EncodeToString(uid[:]),
+		Identity:   req.Identity,
+		Info:       req.Info,
+		PublicKey:  req.PublicKey,
+		TenantID:   req.TenantID,
+		LastSeen:   clock.Now(),
+		Connection: req.Connection,
+		Containers: req.Containers,
 	}
 
 	// The order here is critical as we don't want to register devices if the tenant id is invalid
@@ -219,18 +229,12 @@ func (s *service) AuthPublicKey(ctx context.Context, req *models.PublicKeyAuthRe
 		return nil, err
 	}
 
-	block, _ := pem.Decode(privKey.Data)
-	if block == nil {
-		return nil, err
-	}
-
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	key, err := ssh.ParseRawPrivateKey(privKey.Data)
 	if err != nil {
 		return nil, err
 	}
 
-	digest := sha256.Sum256([]byte(req.Data))
-	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	signature, err := signPublicKeyAuth(key, []byte(req.Data))
 	if err != nil {
 		return nil, err
 	}
@@ -240,6 +244,92 @@ func (s *service) AuthPublicKey(ctx context.Context, req *models.PublicKeyAuthRe
 	}, nil
 }
 
+// signPublicKeyAuth signs the data of a public key authentication request.
+// RSA and ECDSA keys sign the SHA256 digest of the data, while Ed25519 keys
+// sign the data itself.
+func signPublicKeyAuth(key interface{}, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		return ecdsa.SignASN1(rand.Reader, key, digest[:])
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, data), nil
+	case *ed25519.PrivateKey:
+		return ed25519.Sign(*key, data), nil
+	}
+
+	return nil, ErrUnsupportedKeyType
+}
+
+// AuthBreakGlassKeys returns the public keys of the namespace of the device,
+// signed to be cached by its agent for the logins on the local SSH listener.
+func (s *service) AuthBreakGlassKeys(ctx context.Context, uid models.UID) (*models.BreakGlassKeys, error) {
+	device, err := s.store.DeviceGet(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Status != "accepted" {
+		return nil, ErrUnauthorized
+	}
+
+	ctx = context.WithValue(ctx, "tenant", device.TenantID) //nolint:revive,staticcheck
+
+	list, _, err := s.store.PublicKeyList(ctx, paginator.Query{Page: 1, PerPage: -1})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := s.store.NamespaceGet(ctx, device.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &models.BreakGlassKeys{
+		DeviceUID:      device.UID,
+		DeviceName:     device.Name,
+		TenantID:       device.TenantID,
+		Keys:           []models.BreakGlassKey{},
+		IssuedAt:       clock.Now().UTC(),
+		PortForwarding: namespace.Settings != nil && namespace.Settings.PortForwarding,
+	}
+
+	for _, key := range list {
+		if key.TenantID != device.TenantID {
+			continue
+		}
+
+		keys.Keys = append(keys.Keys, models.BreakGlassKey{
+			Fingerprint: key.Fingerprint,
+			Data:        key.Data,
+			Hostname:    key.Hostname,
+		})
+	}
+
+	data, err := keys.SignedData()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signPublicKeyAuth(s.privKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := x509.MarshalPKIXPublicKey(s.pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keys.Signature = base64.StdEncoding.EncodeToString(signature)
+	keys.ServerKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: serverKey}))
+
+	return keys, nil
+}
+
 func (s *service) AuthSwapToken(ctx context.Context, id, tenant string) (*models.UserAuthResponse, error) {
 	namespace, err := s.store.NamespaceGet(ctx, tenant)
 	if err != nil {
@@ -2,10 +2,17 @@ package authsvc
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"testing"
 	"time"
@@ -13,6 +20,7 @@ import (
 	"github.com/cnf/structhash"
 	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/api/store/mocks"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/clock"
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/stretchr/testify/assert"
@@ -196,3 +204,120 @@ func TestAuthUserInfo(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestAuthPublicKey(t *testing.T) {
+	mock := &mocks.Store{}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	s := NewService(store.Store(mock), privateKey, &privateKey.PublicKey)
+
+	ctx := context.TODO()
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	ecdsaData, err := x509.MarshalECPrivateKey(ecdsaKey)
+	assert.NoError(t, err)
+
+	ed25519Pub, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	ed25519Data, err := x509.MarshalPKCS8PrivateKey(ed25519Key)
+	assert.NoError(t, err)
+
+	data := "data"
+	digest := sha256.Sum256([]byte(data))
+
+	cases := []struct {
+		name   string
+		block  *pem.Block
+		verify func(signature []byte) bool
+	}{
+		{
+			name:  "AuthPublicKey signs with a RSA key",
+			block: &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)},
+			verify: func(signature []byte) bool {
+				return rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature) == nil
+			},
+		},
+		{
+			name:  "AuthPublicKey signs with a ECDSA key",
+			block: &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecdsaData},
+			verify: func(signature []byte) bool {
+				return ecdsa.VerifyASN1(&ecdsaKey.PublicKey, digest[:], signature)
+			},
+		},
+		{
+			name:  "AuthPublicKey signs with a Ed25519 key",
+			block: &pem.Block{Type: "PRIVATE KEY", Bytes: ed25519Data},
+			verify: func(signature []byte) bool {
+				return ed25519.Verify(ed25519Pub, []byte(data), signature)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock.On("PrivateKeyGet", ctx, "fingerprint").
+				Return(&models.PrivateKey{Data: pem.EncodeToMemory(tc.block), Fingerprint: "fingerprint"}, nil).Once()
+
+			res, err := s.AuthPublicKey(ctx, &models.PublicKeyAuthRequest{Fingerprint: "fingerprint", Data: data})
+			assert.NoError(t, err)
+
+			signature, err := base64.StdEncoding.DecodeString(res.Signature)
+			assert.NoError(t, err)
+			assert.True(t, tc.verify(signature))
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestAuthBreakGlassKeys(t *testing.T) {
+	mock := &mocks.Store{}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	s := NewService(store.Store(mock), privateKey, &privateKey.PublicKey)
+
+	ctx := context.TODO()
+
+	pending := &models.Device{UID: "pending", TenantID: "tenant", Status: "pending"}
+	device := &models.Device{UID: "uid", Name: "name", TenantID: "tenant", Status: "accepted"}
+
+	mock.On("DeviceGet", ctx, models.UID(pending.UID)).Return(pending, nil).Once()
+
+	_, err = s.AuthBreakGlassKeys(ctx, models.UID(pending.UID))
+	assert.Equal(t, ErrUnauthorized, err)
+
+	publicKeys := []models.PublicKey{
+		{Data: []byte("key"), Fingerprint: "fingerprint", TenantID: "tenant", PublicKeyFields: models.PublicKeyFields{Hostname: ".*"}},
+		{Data: []byte("other"), Fingerprint: "other", TenantID: "other"},
+	}
+
+	namespace := &models.Namespace{TenantID: "tenant", Settings: &models.NamespaceSettings{PortForwarding: true}}
+
+	mock.On("DeviceGet", ctx, models.UID(device.UID)).Return(device, nil).Once()
+	mock.On("PublicKeyList", context.WithValue(ctx, "tenant", "tenant"), paginator.Query{Page: 1, PerPage: -1}). //nolint:revive,staticcheck
+		Return(publicKeys, len(publicKeys), nil).Once()
+	mock.On("NamespaceGet", context.WithValue(ctx, "tenant", "tenant"), "tenant").Return(namespace, nil).Once() //nolint:revive,staticcheck
+
+	keys, err := s.AuthBreakGlassKeys(ctx, models.UID(device.UID))
+	assert.NoError(t, err)
+	assert.Equal(t, "name", keys.DeviceName)
+	assert.True(t, keys.PortForwarding)
+	assert.Equal(t, []models.BreakGlassKey{{Fingerprint: "fingerprint", Data: []byte("key"), Hostname: ".*"}}, keys.Keys)
+
+	data, err := keys.SignedData()
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+	signature, err := base64.StdEncoding.DecodeString(keys.Signature)
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+	mock.AssertExpectations(t)
+}
@@ -0,0 +1,123 @@
+package deviceadm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// exportedDevice is an accepted device of the namespace, addressed by its
+// namespace qualified name or its UID when reached through the gateway used
+// as a jump host.
+type exportedDevice struct {
+	hosts   []string
+	hostKey ssh.PublicKey
+}
+
+// exportDevices returns the accepted devices of the namespace which hold a
+// valid public key.
+func (s *service) exportDevices(ctx context.Context, tenant string) ([]exportedDevice, error) {
+	devices, _, err := s.store.DeviceList(ctx, paginator.Query{Page: 1, PerPage: -1}, nil, "accepted", "name", "asc")
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]exportedDevice, 0, len(devices))
+
+	for i := range devices {
+		device := &devices[i]
+		if device.TenantID != tenant {
+			continue
+		}
+
+		pubKey, err := device.HostKey()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"device": device.UID, "err": err}).Warning("Failed to parse the device public key")
+
+			continue
+		}
+
+		hostKey, err := ssh.NewPublicKey(pubKey)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"device": device.UID, "err": err}).Warning("Failed to parse the device public key")
+
+			continue
+		}
+
+		exported = append(exported, exportedDevice{
+			hosts:   []string{strings.ToLower(fmt.Sprintf("%s.%s", device.Namespace, device.Name)), device.UID},
+			hostKey: hostKey,
+		})
+	}
+
+	return exported, nil
+}
+
+// ExportKnownHosts returns the host keys of the accepted devices of the
+// namespace in the known_hosts format, so the clients reaching them through
+// the gateway used as a jump host can verify them.
+func (s *service) ExportKnownHosts(ctx context.Context, tenant string) ([]byte, error) {
+	devices, err := s.exportDevices(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	for _, device := range devices {
+		buf.WriteString(strings.Join(device.hosts, ","))
+		buf.WriteByte(' ')
+		buf.Write(ssh.MarshalAuthorizedKey(device.hostKey))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// hostnameRegexp matches the DNS host names.
+var hostnameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// validGateway reports whether the gateway is a host[:port] address, so it can
+// not carry other ssh_config directives.
+func validGateway(gateway string) bool {
+	host := gateway
+	if h, port, err := net.SplitHostPort(gateway); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+
+		host = h
+	}
+
+	return net.ParseIP(host) != nil || hostnameRegexp.MatchString(host)
+}
+
+// ExportSSHConfig returns the ssh_config entries which reach the accepted
+// devices of the namespace through the gateway used as a jump host.
+func (s *service) ExportSSHConfig(ctx context.Context, tenant, gateway string) ([]byte, error) {
+	if !validGateway(gateway) {
+		return nil, ErrInvalidGateway
+	}
+
+	devices, err := s.exportDevices(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	for _, device := range devices {
+		fmt.Fprintf(&buf, "Host %s\n", strings.Join(device.hosts, " "))
+		fmt.Fprintf(&buf, "    ProxyJump %s\n", gateway)
+		fmt.Fprintf(&buf, "    StrictHostKeyChecking yes\n\n")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -18,6 +18,7 @@ var (
 	ErrUnauthorized          = errors.New("unauthorized")
 	ErrMaxDeviceCountReached = errors.New("maximum number of accepted devices reached")
 	ErrDuplicatedDeviceName  = errors.New("the name already exists in the namespace")
+	ErrInvalidGateway        = errors.New("invalid gateway address")
 )
 
 type Service interface {
@@ -28,6 +29,10 @@ type Service interface {
 	LookupDevice(ctx context.Context, namespace, name string) (*models.Device, error)
 	UpdateDeviceStatus(ctx context.Context, uid models.UID, online bool) error
 	UpdatePendingStatus(ctx context.Context, uid models.UID, status, tenant, ownerID string) error
+	CreateBreakGlassLogins(ctx context.Context, uid models.UID, logins []models.BreakGlassLogin) error
+	ListBreakGlassLogins(ctx context.Context, uid models.UID, tenant string, pagination paginator.Query) ([]models.BreakGlassLogin, int, error)
+	ExportKnownHosts(ctx context.Context, tenant string) ([]byte, error)
+	ExportSSHConfig(ctx context.Context, tenant, gateway string) ([]byte, error)
 }
 
 type service struct {
@@ -130,8 +135,8 @@ func (s *service) UpdatePendingStatus(ctx context.Context, uid models.UID, statu
 	}
 
 	if status == "accepted" { //nolint:nestif
-		sameMacDev, err := s.store.DeviceGetByMac(ctx, device.Identity.MAC, device.TenantID, "accepted")
-		if err != nil && err != store.ErrNoDocuments {
+		sameMacDev, err := s.getSameIdentityDevice(ctx, device)
+		if err != nil {
 			return err
 		}
 
@@ -159,3 +164,68 @@ func (s *service) UpdatePendingStatus(ctx context.Context, uid models.UID, statu
 
 	return s.store.DeviceUpdateStatus(ctx, uid, status)
 }
+
+// getSameIdentityDevice returns the accepted device with the same identity of
+// device, which means the device has been registered again.
+func (s *service) getSameIdentityDevice(ctx context.Context, device *models.Device) (*models.Device, error) {
+	identity := device.Identity
+	if identity == nil {
+		return nil, nil
+	}
+
+	if identity.Source != "" && identity.ID != "" {
+		dev, err := s.store.DeviceGetByIdentity(ctx, identity.Source, identity.ID, device.TenantID, "accepted")
+		if err != nil && err != store.ErrNoDocuments {
+			return nil, err
+		}
+
+		if dev != nil {
+			return dev, nil
+		}
+	}
+
+	if identity.MAC == "" {
+		return nil, nil
+	}
+
+	dev, err := s.store.DeviceGetByMac(ctx, identity.MAC, device.TenantID, "accepted")
+	if err != nil && err != store.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Devices sharing the same MAC address (e.g. containers on the same
+	// host) are told apart by their identity when both have one.
+	if dev != nil && identity.Source != "" && dev.Identity != nil && dev.Identity.Source != "" {
+		return nil, nil
+	}
+
+	return dev, nil
+}
+
+// CreateBreakGlassLogins records the logins made through the local SSH
+// listener of the device, as reported by its agent.
+func (s *service) CreateBreakGlassLogins(ctx context.Context, uid models.UID, logins []models.BreakGlassLogin) error {
+	device, err := s.store.DeviceGet(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if len(logins) == 0 {
+		return nil
+	}
+
+	for i := range logins {
+		logins[i].DeviceUID = device.UID
+		logins[i].TenantID = device.TenantID
+	}
+
+	return s.store.BreakGlassLoginCreate(ctx, logins)
+}
+
+func (s *service) ListBreakGlassLogins(ctx context.Context, uid models.UID, tenant string, pagination paginator.Query) ([]models.BreakGlassLogin, int, error) {
+	if _, err := s.store.DeviceGetByUID(ctx, uid, tenant); err != nil {
+		return nil, 0, err
+	}
+
+	return s.store.BreakGlassLoginList(ctx, uid, pagination)
+}
@@ -2,8 +2,12 @@ package deviceadm
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"testing"
 
@@ -13,6 +17,7 @@ import (
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/shellhub-io/shellhub/pkg/validator"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestListDevices(t *testing.T) {
@@ -627,6 +632,34 @@ func TestUpdatePendingStatus(t *testing.T) {
 			},
 			expected: nil,
 		},
+		{
+			name:   "UpdatePendingStatus succeeds when the device has the same identity source",
+			uid:    models.UID("uid_machine_id"),
+			tenant: namespace.TenantID,
+			id:     user.ID,
+			requiredMocks: func() {
+				machineID := &models.DeviceIdentity{Source: models.DeviceIdentitySourceMachineID, ID: "machine_id"}
+				newDevice := &models.Device{UID: "uid_machine_id", Name: "name", TenantID: "tenant", Identity: machineID}
+				oldDevice := &models.Device{UID: "old_uid_machine_id", Name: "old_name", TenantID: "tenant", Identity: machineID}
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, newDevice.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceGetByUID", ctx, models.UID(newDevice.UID), newDevice.TenantID).
+					Return(newDevice, nil).Once()
+				mock.On("DeviceGetByIdentity", ctx, models.DeviceIdentitySourceMachineID, "machine_id", newDevice.TenantID, "accepted").
+					Return(oldDevice, nil).Once()
+				mock.On("SessionUpdateDeviceUID", ctx, models.UID(oldDevice.UID), models.UID(newDevice.UID)).
+					Return(nil).Once()
+				mock.On("DeviceDelete", ctx, models.UID(oldDevice.UID)).
+					Return(nil).Once()
+				mock.On("DeviceRename", ctx, models.UID(newDevice.UID), oldDevice.Name).
+					Return(nil).Once()
+				mock.On("DeviceUpdateStatus", ctx, models.UID(newDevice.UID), "accepted").
+					Return(nil).Once()
+			},
+			expected: nil,
+		},
 	}
 
 	for _, tc := range cases {
@@ -639,3 +672,167 @@ func TestUpdatePendingStatus(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestCreateBreakGlassLogins(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	Err := errors.New("error")
+
+	ctx := context.TODO()
+
+	device := &models.Device{UID: "uid", TenantID: "tenant"}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		uid           models.UID
+		logins        []models.BreakGlassLogin
+		expected      error
+	}{
+		{
+			name: "CreateBreakGlassLogins fails when the device is not found",
+			requiredMocks: func() {
+				mock.On("DeviceGet", ctx, models.UID("_uid")).
+					Return(nil, Err).Once()
+			},
+			uid:      models.UID("_uid"),
+			logins:   []models.BreakGlassLogin{{Username: "root"}},
+			expected: Err,
+		},
+		{
+			name: "CreateBreakGlassLogins succeeds",
+			requiredMocks: func() {
+				mock.On("DeviceGet", ctx, models.UID(device.UID)).
+					Return(device, nil).Once()
+				mock.On("BreakGlassLoginCreate", ctx, []models.BreakGlassLogin{{DeviceUID: "uid", TenantID: "tenant", Username: "root"}}).
+					Return(nil).Once()
+			},
+			uid:      models.UID("uid"),
+			logins:   []models.BreakGlassLogin{{DeviceUID: "other", TenantID: "other", Username: "root"}},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.CreateBreakGlassLogins(ctx, tc.uid, tc.logins)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestListBreakGlassLogins(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	Err := errors.New("error")
+
+	ctx := context.TODO()
+
+	device := &models.Device{UID: "uid", TenantID: "tenant"}
+	logins := []models.BreakGlassLogin{{DeviceUID: "uid", TenantID: "tenant", Username: "root"}}
+	query := paginator.Query{Page: 1, PerPage: 10}
+
+	type Expected struct {
+		logins []models.BreakGlassLogin
+		count  int
+		err    error
+	}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		uid           models.UID
+		tenant        string
+		expected      Expected
+	}{
+		{
+			name: "ListBreakGlassLogins fails when the device is not in the namespace",
+			requiredMocks: func() {
+				mock.On("DeviceGetByUID", ctx, models.UID("uid"), "other").
+					Return(nil, Err).Once()
+			},
+			uid:      models.UID("uid"),
+			tenant:   "other",
+			expected: Expected{nil, 0, Err},
+		},
+		{
+			name: "ListBreakGlassLogins succeeds",
+			requiredMocks: func() {
+				mock.On("DeviceGetByUID", ctx, models.UID("uid"), "tenant").
+					Return(device, nil).Once()
+				mock.On("BreakGlassLoginList", ctx, models.UID("uid"), query).
+					Return(logins, len(logins), nil).Once()
+			},
+			uid:      models.UID("uid"),
+			tenant:   "tenant",
+			expected: Expected{logins, len(logins), nil},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			returnedLogins, count, err := s.ListBreakGlassLogins(ctx, tc.uid, tc.tenant, query)
+			assert.Equal(t, tc.expected, Expected{returnedLogins, count, err})
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestExportKnownHosts(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	data, err := x509.MarshalPKIXPublicKey(pubKey)
+	assert.NoError(t, err)
+
+	hostKey, err := ssh.NewPublicKey(pubKey)
+	assert.NoError(t, err)
+
+	devices := []models.Device{
+		{
+			UID:       "uid",
+			Name:      "device",
+			Namespace: "namespace",
+			TenantID:  "tenant",
+			PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: data})),
+		},
+		{UID: "uid2", Name: "invalid", Namespace: "namespace", TenantID: "tenant", PublicKey: "invalid"},
+		{UID: "uid3", Name: "other", Namespace: "other", TenantID: "other"},
+	}
+
+	mock.On("DeviceList", ctx, paginator.Query{Page: 1, PerPage: -1}, []models.Filter(nil), "accepted", "name", "asc").
+		Return(devices, len(devices), nil).Times(5)
+
+	knownHosts, err := s.ExportKnownHosts(ctx, "tenant")
+	assert.NoError(t, err)
+	assert.Equal(t, "namespace.device,uid "+string(ssh.MarshalAuthorizedKey(hostKey)), string(knownHosts))
+
+	config, err := s.ExportSSHConfig(ctx, "tenant", "shellhub.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "Host namespace.device uid\n    ProxyJump shellhub.example.com\n    StrictHostKeyChecking yes\n\n", string(config))
+
+	// The gateway can not carry other directives.
+	for _, gateway := range []string{"shellhub.example.com\n    ProxyCommand sh", "shellhub.example.com:0", "-oProxyCommand=sh", ""} {
+		_, err = s.ExportSSHConfig(ctx, "tenant", gateway)
+		assert.Equal(t, ErrInvalidGateway, err)
+	}
+
+	for _, gateway := range []string{"shellhub.example.com:2222", "192.168.1.1", "[::1]:2222"} {
+		_, err = s.ExportSSHConfig(ctx, "tenant", gateway)
+		assert.NoError(t, err)
+	}
+
+	mock.AssertExpectations(t)
+}
@@ -13,6 +13,7 @@ require (
 	github.com/labstack/echo/v4 v4.3.0
 	github.com/leodido/go-urn v1.2.1 // indirect
 	github.com/mitchellh/mapstructure v1.4.1
+	github.com/prometheus/client_golang v1.11.1
 	github.com/shellhub-io/shellhub v0.5.2
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v0.0.7
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+// Dispatcher runs the command of a job on a device.
+type Dispatcher interface {
+	Exec(ctx context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error)
+	// Diagnostics returns the tar bundle with the diagnostics collected by
+	// the agent of the device.
+	Diagnostics(ctx context.Context, device models.UID) (io.ReadCloser, error)
+}
+
+// DefaultDispatcher sends the commands to the agents through the reverse
+// connection held by the SSH server.
+var DefaultDispatcher Dispatcher = &tunnelDispatcher{address: "http://ssh:8080"}
+
+type tunnelDispatcher struct {
+	address string
+}
+
+func (d *tunnelDispatcher) Exec(ctx context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error) {
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/devices/%s/exec", d.address, device), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+
+		return nil, fmt.Errorf("failed to execute command: %s", strings.TrimSpace(string(msg)))
+	}
+
+	out := &models.JobOutput{}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (d *tunnelDispatcher) Diagnostics(ctx context.Context, device models.UID) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/devices/%s/diagnostics", d.address, device), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+
+		msg, _ := ioutil.ReadAll(res.Body)
+
+		return nil, fmt.Errorf("failed to collect diagnostics: %s", strings.TrimSpace(string(msg)))
+	}
+
+	return res.Body, nil
+}
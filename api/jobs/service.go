@@ -0,0 +1,310 @@
+package jobs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	utils "github.com/shellhub-io/shellhub/api/pkg/namespace"
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/shellhub-io/shellhub/pkg/uuid"
+	"github.com/shellhub-io/shellhub/pkg/validator"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrInvalidJob        = errors.New("invalid job")
+	ErrDeviceNotAccepted = errors.New("device is not accepted")
+	ErrNoDevices         = errors.New("no accepted device matches the filter")
+	ErrTooManyDevices    = errors.New("too many devices match the filter")
+	ErrDeviceUnreachable = errors.New("device could not be reached")
+)
+
+const (
+	// DefaultTimeout is the time in seconds a job command is allowed to run
+	// when no timeout is requested.
+	DefaultTimeout = 60
+
+	// MaxDevices is the maximum number of devices targeted by a single job.
+	MaxDevices = 100
+
+	// maxConcurrentDevices is the number of devices a job runs at once.
+	maxConcurrentDevices = 10
+
+	// dispatchGracePeriod is the time given to the agent to report the output
+	// of a command after its timeout.
+	dispatchGracePeriod = 10 * time.Second
+)
+
+// Request is a command to be executed on a single device, or on the devices
+// matching the filter.
+type Request struct {
+	Command string `json:"command" validate:"required"`
+	Timeout int    `json:"timeout" validate:"min=0,max=3600"`
+	Filter  string `json:"filter"`
+}
+
+type Service interface {
+	ListJobs(ctx context.Context, pagination paginator.Query) ([]models.Job, int, error)
+	GetJob(ctx context.Context, uid models.UID) (*models.Job, error)
+	ExecDevice(ctx context.Context, uid models.UID, req *Request, tenant, ownerID, username string) (*models.Job, error)
+	ExecFleet(ctx context.Context, req *Request, tenant, ownerID, username string) (*models.Job, error)
+	CollectDiagnostics(ctx context.Context, uid models.UID, tenant, ownerID, username string) (io.ReadCloser, error)
+}
+
+type service struct {
+	store      store.Store
+	dispatcher Dispatcher
+	wg         sync.WaitGroup
+}
+
+func NewService(store store.Store, dispatcher Dispatcher) Service {
+	return &service{store: store, dispatcher: dispatcher}
+}
+
+func (s *service) ListJobs(ctx context.Context, pagination paginator.Query) ([]models.Job, int, error) {
+	return s.store.JobList(ctx, pagination)
+}
+
+func (s *service) GetJob(ctx context.Context, uid models.UID) (*models.Job, error) {
+	return s.store.JobGet(ctx, uid)
+}
+
+func (s *service) ExecDevice(ctx context.Context, uid models.UID, req *Request, tenant, ownerID, username string) (*models.Job, error) {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if _, err := validator.ValidateStruct(req); err != nil {
+		return nil, ErrInvalidJob
+	}
+
+	device, err := s.store.DeviceGetByUID(ctx, uid, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Status != "accepted" {
+		return nil, ErrDeviceNotAccepted
+	}
+
+	return s.start(ctx, newJob(req, tenant, username, "", []models.Device{*device}))
+}
+
+func (s *service) ExecFleet(ctx context.Context, req *Request, tenant, ownerID, username string) (*models.Job, error) {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	if _, err := validator.ValidateStruct(req); err != nil {
+		return nil, ErrInvalidJob
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(req.Filter)
+	if err != nil {
+		return nil, ErrInvalidJob
+	}
+
+	var filter []models.Filter
+	if err := json.Unmarshal(raw, &filter); len(raw) > 0 && err != nil {
+		return nil, ErrInvalidJob
+	}
+
+	devices, count, err := s.store.DeviceList(ctx, paginator.Query{Page: 1, PerPage: MaxDevices}, filter, "accepted", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case count == 0:
+		return nil, ErrNoDevices
+	case count > MaxDevices:
+		return nil, ErrTooManyDevices
+	}
+
+	return s.start(ctx, newJob(req, tenant, username, req.Filter, devices))
+}
+
+// CollectDiagnostics requests the agent of the device to collect its
+// diagnostics, returned as a tar bundle. They expose the logs and the
+// configuration of the agent, so only the owner is allowed to collect them.
+func (s *service) CollectDiagnostics(ctx context.Context, uid models.UID, tenant, ownerID, username string) (io.ReadCloser, error) {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	device, err := s.store.DeviceGetByUID(ctx, uid, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Status != "accepted" {
+		return nil, ErrDeviceNotAccepted
+	}
+
+	log := logrus.WithFields(logrus.Fields{
+		"device": uid,
+		"tenant": tenant,
+		"user":   username,
+	})
+
+	log.Info("Collecting device diagnostics")
+
+	bundle, err := s.dispatcher.Diagnostics(ctx, uid)
+	if err != nil {
+		log.WithError(err).Warning("Failed to collect device diagnostics")
+
+		return nil, ErrDeviceUnreachable
+	}
+
+	return bundle, nil
+}
+
+func newJob(req *Request, tenant, username, filter string, devices []models.Device) *models.Job {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	now := clock.Now()
+
+	job := &models.Job{
+		UID:       uuid.Generate(),
+		TenantID:  tenant,
+		Command:   req.Command,
+		Timeout:   timeout,
+		Filter:    filter,
+		CreatedBy: username,
+		CreatedAt: now,
+		Results:   make([]models.JobResult, 0, len(devices)),
+		Audit: []models.JobAuditEntry{
+			{Time: now, Action: "created", Actor: username},
+		},
+	}
+
+	for _, device := range devices {
+		job.Results = append(job.Results, models.JobResult{
+			Device: models.UID(device.UID),
+			Status: models.JobStatusPending,
+		})
+	}
+
+	return job
+}
+
+// start stores the job and runs it in background, so its results are
+// retrieved later through GetJob.
+func (s *service) start(ctx context.Context, job *models.Job) (*models.Job, error) {
+	if err := s.store.JobCreate(ctx, job); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"job":     job.UID,
+		"tenant":  job.TenantID,
+		"user":    job.CreatedBy,
+		"devices": len(job.Results),
+	}).Info("Job created")
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		s.run(job)
+	}()
+
+	return job, nil
+}
+
+// run executes the job on its devices, a few at a time.
+func (s *service) run(job *models.Job) {
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, maxConcurrentDevices)
+
+	for _, result := range job.Results {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(device models.UID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.runDevice(job, device)
+		}(result.Device)
+	}
+
+	wg.Wait()
+}
+
+func (s *service) runDevice(job *models.Job, device models.UID) {
+	result := &models.JobResult{
+		Device:    device,
+		Status:    models.JobStatusRunning,
+		StartedAt: clock.Now(),
+	}
+
+	s.updateResult(job, result, "started", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(job.Timeout)*time.Second+dispatchGracePeriod)
+	defer cancel()
+
+	out, err := s.dispatcher.Exec(ctx, device, &models.JobCommand{
+		Command: job.Command,
+		Timeout: job.Timeout,
+	})
+
+	result.FinishedAt = clock.Now()
+
+	if err != nil {
+		result.Status = models.JobStatusFailed
+		result.Error = err.Error()
+
+		s.updateResult(job, result, "failed", err.Error())
+
+		return
+	}
+
+	result.Status = models.JobStatusFinished
+	result.Stdout = truncate(out.Stdout, models.JobOutputLimit)
+	result.Stderr = truncate(out.Stderr, models.JobOutputLimit)
+	result.ExitCode = out.ExitCode
+	result.Signal = out.Signal
+
+	s.updateResult(job, result, "finished", fmt.Sprintf("exit code %d", out.ExitCode))
+}
+
+func (s *service) updateResult(job *models.Job, result *models.JobResult, action, message string) {
+	entry := &models.JobAuditEntry{
+		Time:    clock.Now(),
+		Action:  action,
+		Device:  result.Device,
+		Message: message,
+	}
+
+	if err := s.store.JobUpdateResult(context.Background(), models.UID(job.UID), result, entry); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"job":    job.UID,
+			"device": result.Device,
+			"err":    err,
+		}).Error("Failed to update job result")
+	}
+}
+
+func truncate(s string, limit int) string {
+	if len(s) > limit {
+		return s[:limit]
+	}
+
+	return s
+}
@@ -0,0 +1,361 @@
+package jobs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/api/store/mocks"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	mocklib "github.com/stretchr/testify/mock"
+)
+
+type dispatcherFunc func(ctx context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error)
+
+func (f dispatcherFunc) Exec(ctx context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error) {
+	return f(ctx, device, cmd)
+}
+
+// Diagnostics returns the UID of the device as the bundle, or the error of
+// the command sent to it.
+func (f dispatcherFunc) Diagnostics(ctx context.Context, device models.UID) (io.ReadCloser, error) {
+	if _, err := f(ctx, device, &models.JobCommand{}); err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(strings.NewReader(string(device))), nil
+}
+
+func TestExecDevice(t *testing.T) {
+	mock := &mocks.Store{}
+
+	dispatcher := dispatcherFunc(func(_ context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error) {
+		if device == "offline" {
+			return nil, errors.New("device is offline")
+		}
+
+		return &models.JobOutput{Stdout: cmd.Command, ExitCode: 3}, nil
+	})
+
+	s := NewService(store.Store(mock), dispatcher)
+
+	ctx := context.TODO()
+
+	user := &models.User{Name: "name", Username: "username", ID: "id"}
+	user2 := &models.User{Name: "name2", Username: "username2", ID: "id2"}
+	namespace := &models.Namespace{Name: "group1", Owner: "id", TenantID: "tenant"}
+	pending := &models.Device{UID: "pending", TenantID: "tenant", Status: "pending"}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		uid           models.UID
+		req           *Request
+		id            string
+		expected      error
+	}{
+		{
+			name: "ExecDevice fails when the user is not the owner",
+			uid:  models.UID("uid"),
+			req:  &Request{Command: "uptime"},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user2.ID, false).
+					Return(user2, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+			},
+			id:       user2.ID,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "ExecDevice fails when the command is empty",
+			uid:  models.UID("uid"),
+			req:  &Request{},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+			},
+			id:       user.ID,
+			expected: ErrInvalidJob,
+		},
+		{
+			name: "ExecDevice fails when the device is not accepted",
+			uid:  models.UID(pending.UID),
+			req:  &Request{Command: "uptime"},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceGetByUID", ctx, models.UID(pending.UID), namespace.TenantID).
+					Return(pending, nil).Once()
+			},
+			id:       user.ID,
+			expected: ErrDeviceNotAccepted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			_, err := s.ExecDevice(ctx, tc.uid, tc.req, namespace.TenantID, tc.id, user.Username)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	t.Run("ExecDevice stores the output of the command", func(t *testing.T) {
+		device := &models.Device{UID: "uid", TenantID: "tenant", Status: "accepted"}
+
+		mock.On("UserGetByID", ctx, user.ID, false).
+			Return(user, 0, nil).Once()
+		mock.On("NamespaceGet", ctx, namespace.TenantID).
+			Return(namespace, nil).Once()
+		mock.On("DeviceGetByUID", ctx, models.UID(device.UID), namespace.TenantID).
+			Return(device, nil).Once()
+		mock.On("JobCreate", ctx, mocklib.AnythingOfType("*models.Job")).
+			Return(nil).Once()
+
+		var results []models.JobResult
+
+		mock.On("JobUpdateResult", mocklib.Anything, mocklib.AnythingOfType("models.UID"), mocklib.AnythingOfType("*models.JobResult"), mocklib.AnythingOfType("*models.JobAuditEntry")).
+			Run(func(args mocklib.Arguments) {
+				results = append(results, *args.Get(2).(*models.JobResult))
+			}).
+			Return(nil).Twice()
+
+		job, err := s.ExecDevice(ctx, models.UID(device.UID), &Request{Command: "uptime"}, namespace.TenantID, user.ID, user.Username)
+		assert.NoError(t, err)
+		assert.Equal(t, user.Username, job.CreatedBy)
+		assert.Equal(t, DefaultTimeout, job.Timeout)
+		assert.Equal(t, []models.JobResult{{Device: models.UID(device.UID), Status: models.JobStatusPending}}, job.Results)
+
+		s.(*service).wg.Wait()
+
+		assert.Len(t, results, 2)
+		assert.Equal(t, models.JobStatusRunning, results[0].Status)
+		assert.Equal(t, models.JobStatusFinished, results[1].Status)
+		assert.Equal(t, "uptime", results[1].Stdout)
+		assert.Equal(t, 3, results[1].ExitCode)
+	})
+
+	mock.AssertExpectations(t)
+}
+
+func TestExecFleet(t *testing.T) {
+	mock := &mocks.Store{}
+
+	dispatcher := dispatcherFunc(func(_ context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error) {
+		return nil, errors.New("device is offline")
+	})
+
+	s := NewService(store.Store(mock), dispatcher)
+
+	ctx := context.TODO()
+
+	user := &models.User{Name: "name", Username: "username", ID: "id"}
+	namespace := &models.Namespace{Name: "group1", Owner: "id", TenantID: "tenant"}
+	query := paginator.Query{Page: 1, PerPage: MaxDevices}
+	filter := base64.StdEncoding.EncodeToString([]byte(`[{"type":"property","params":{"name":"name","operator":"like","value":"web"}}]`))
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		req           *Request
+		expected      error
+	}{
+		{
+			name: "ExecFleet fails when the filter is invalid",
+			req:  &Request{Command: "uptime", Filter: "invalid"},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+			},
+			expected: ErrInvalidJob,
+		},
+		{
+			name: "ExecFleet fails when no device matches the filter",
+			req:  &Request{Command: "uptime", Filter: filter},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceList", ctx, query, mocklib.Anything, "accepted", "", "").
+					Return([]models.Device{}, 0, nil).Once()
+			},
+			expected: ErrNoDevices,
+		},
+		{
+			name: "ExecFleet fails when too many devices match the filter",
+			req:  &Request{Command: "uptime", Filter: filter},
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceList", ctx, query, mocklib.Anything, "accepted", "", "").
+					Return([]models.Device{}, MaxDevices+1, nil).Once()
+			},
+			expected: ErrTooManyDevices,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			_, err := s.ExecFleet(ctx, tc.req, namespace.TenantID, user.ID, user.Username)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	t.Run("ExecFleet records the failure of each device", func(t *testing.T) {
+		devices := []models.Device{{UID: "uid1"}, {UID: "uid2"}}
+
+		mock.On("UserGetByID", ctx, user.ID, false).
+			Return(user, 0, nil).Once()
+		mock.On("NamespaceGet", ctx, namespace.TenantID).
+			Return(namespace, nil).Once()
+		mock.On("DeviceList", ctx, query, mocklib.Anything, "accepted", "", "").
+			Return(devices, len(devices), nil).Once()
+		mock.On("JobCreate", ctx, mocklib.AnythingOfType("*models.Job")).
+			Return(nil).Once()
+
+		failed := make(chan models.JobResult, len(devices))
+
+		mock.On("JobUpdateResult", mocklib.Anything, mocklib.AnythingOfType("models.UID"), mocklib.AnythingOfType("*models.JobResult"), mocklib.AnythingOfType("*models.JobAuditEntry")).
+			Run(func(args mocklib.Arguments) {
+				if result := *args.Get(2).(*models.JobResult); result.Status == models.JobStatusFailed {
+					failed <- result
+				}
+			}).
+			Return(nil).Times(2 * len(devices))
+
+		job, err := s.ExecFleet(ctx, &Request{Command: "uptime", Filter: filter}, namespace.TenantID, user.ID, user.Username)
+		assert.NoError(t, err)
+		assert.Len(t, job.Results, len(devices))
+		assert.Equal(t, filter, job.Filter)
+
+		s.(*service).wg.Wait()
+		close(failed)
+
+		for result := range failed {
+			assert.Equal(t, "device is offline", result.Error)
+		}
+	})
+
+	mock.AssertExpectations(t)
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	mock := &mocks.Store{}
+
+	dispatcher := dispatcherFunc(func(_ context.Context, device models.UID, cmd *models.JobCommand) (*models.JobOutput, error) {
+		if device == "offline" {
+			return nil, errors.New("device is offline")
+		}
+
+		return &models.JobOutput{}, nil
+	})
+
+	s := NewService(store.Store(mock), dispatcher)
+
+	ctx := context.TODO()
+
+	user := &models.User{Name: "name", Username: "username", ID: "id"}
+	user2 := &models.User{Name: "name2", Username: "username2", ID: "id2"}
+	namespace := &models.Namespace{Name: "group1", Owner: "id", TenantID: "tenant"}
+	pending := &models.Device{UID: "pending", TenantID: "tenant", Status: "pending"}
+	offline := &models.Device{UID: "offline", TenantID: "tenant", Status: "accepted"}
+	device := &models.Device{UID: "uid", TenantID: "tenant", Status: "accepted"}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		uid           models.UID
+		id            string
+		expected      string
+		expectedErr   error
+	}{
+		{
+			name: "CollectDiagnostics fails when the user is not the owner",
+			uid:  models.UID(device.UID),
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user2.ID, false).
+					Return(user2, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+			},
+			id:          user2.ID,
+			expectedErr: ErrUnauthorized,
+		},
+		{
+			name: "CollectDiagnostics fails when the device is not accepted",
+			uid:  models.UID(pending.UID),
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceGetByUID", ctx, models.UID(pending.UID), namespace.TenantID).
+					Return(pending, nil).Once()
+			},
+			id:          user.ID,
+			expectedErr: ErrDeviceNotAccepted,
+		},
+		{
+			name: "CollectDiagnostics fails when the device is unreachable",
+			uid:  models.UID(offline.UID),
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceGetByUID", ctx, models.UID(offline.UID), namespace.TenantID).
+					Return(offline, nil).Once()
+			},
+			id:          user.ID,
+			expectedErr: ErrDeviceUnreachable,
+		},
+		{
+			name: "CollectDiagnostics returns the bundle of the device",
+			uid:  models.UID(device.UID),
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).
+					Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).
+					Return(namespace, nil).Once()
+				mock.On("DeviceGetByUID", ctx, models.UID(device.UID), namespace.TenantID).
+					Return(device, nil).Once()
+			},
+			id:       user.ID,
+			expected: device.UID,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+
+			bundle, err := s.CollectDiagnostics(ctx, tc.uid, namespace.TenantID, tc.id, user.Username)
+			assert.Equal(t, tc.expectedErr, err)
+
+			if err == nil {
+				data, _ := ioutil.ReadAll(bundle)
+				assert.Equal(t, tc.expected, string(data))
+			}
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
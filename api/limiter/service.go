@@ -0,0 +1,315 @@
+// Package limiter throttles the authentication failures on the SSH gateway,
+// delaying the subjects failing repeatedly and temporarily banning them.
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	storecache "github.com/shellhub-io/shellhub/api/store/cache"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+var ErrBanNotFound = errors.New("ban not found")
+
+// Policy is the limit of the authentication failures of a kind of subject.
+type Policy struct {
+	// Threshold is the number of failures tolerated before delaying.
+	Threshold int
+	// Delay is the delay of the first failure over the threshold, which is
+	// doubled by each further failure up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// BanAfter is the number of failures banning the subject for
+	// BanDuration. Zero never bans it.
+	BanAfter    int
+	BanDuration time.Duration
+	// Window is the time the failures are remembered for since the last
+	// one.
+	Window time.Duration
+}
+
+// DefaultPolicies are the policies of each kind of subject. Only the source IP
+// is banned, since anyone can fail on behalf of a user or a device and a ban
+// would lock their legitimate clients out; those subjects are only delayed.
+var DefaultPolicies = map[string]Policy{
+	models.AuthLimitIP: {
+		Threshold:   5,
+		Delay:       time.Second,
+		MaxDelay:    30 * time.Second,
+		BanAfter:    20,
+		BanDuration: 30 * time.Minute,
+		Window:      15 * time.Minute,
+	},
+	models.AuthLimitUser: {
+		Threshold: 3,
+		Delay:     time.Second,
+		MaxDelay:  30 * time.Second,
+		Window:    15 * time.Minute,
+	},
+	models.AuthLimitDevice: {
+		Threshold: 20,
+		Delay:     time.Second,
+		MaxDelay:  10 * time.Second,
+		Window:    15 * time.Minute,
+	},
+}
+
+const (
+	keyPrefix = "limiter"
+	bansKey   = keyPrefix + "/bans"
+)
+
+type Service interface {
+	Check(ctx context.Context, attempt *models.AuthAttempt) (*models.AuthLimit, error)
+	Failure(ctx context.Context, attempt *models.AuthAttempt) (*models.AuthLimit, error)
+	ListBans(ctx context.Context) ([]models.AuthBan, error)
+	DeleteBan(ctx context.Context, kind, value string) error
+}
+
+type service struct {
+	cache    storecache.Cache
+	policies map[string]Policy
+}
+
+// NewService returns the limiter backed by the cache, which must be shared by
+// all the API instances (e.g. Redis) for the limits to be global.
+func NewService(cache storecache.Cache) Service {
+	return NewServiceWithPolicies(cache, DefaultPolicies)
+}
+
+func NewServiceWithPolicies(cache storecache.Cache, policies map[string]Policy) Service {
+	return &service{cache: cache, policies: policies}
+}
+
+// counter holds the recent failures of a subject. The cache does not support
+// atomic updates, so concurrent failures may be undercounted.
+type counter struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	BannedUntil time.Time `json:"banned_until"`
+}
+
+type subject struct {
+	kind  string
+	value string
+}
+
+func (s subject) key() string {
+	return strings.Join([]string{keyPrefix, s.kind, s.value}, "/")
+}
+
+// subjects returns the subjects of the attempt, whose login user is counted
+// per device.
+func subjects(attempt *models.AuthAttempt) []subject {
+	var list []subject
+
+	if attempt.IPAddress != "" {
+		list = append(list, subject{models.AuthLimitIP, attempt.IPAddress})
+	}
+
+	if attempt.Username != "" && attempt.Device != "" {
+		list = append(list, subject{models.AuthLimitUser, attempt.Username + "@" + attempt.Device})
+	}
+
+	if attempt.Device != "" {
+		list = append(list, subject{models.AuthLimitDevice, attempt.Device})
+	}
+
+	return list
+}
+
+// Check returns the limit of the attempt, which is the longest delay and ban
+// of its subjects.
+func (s *service) Check(ctx context.Context, attempt *models.AuthAttempt) (*models.AuthLimit, error) {
+	limit := &models.AuthLimit{}
+
+	for _, sub := range subjects(attempt) {
+		c, err := s.counter(ctx, sub)
+		if err != nil {
+			return nil, err
+		}
+
+		s.apply(limit, sub, c)
+	}
+
+	return limit, nil
+}
+
+// Failure counts a failure of the attempt, banning the subjects exceeding the
+// limit of their policy, and returns the resulting limit.
+func (s *service) Failure(ctx context.Context, attempt *models.AuthAttempt) (*models.AuthLimit, error) {
+	limit := &models.AuthLimit{}
+	now := clock.Now()
+
+	for _, sub := range subjects(attempt) {
+		policy, ok := s.policies[sub.kind]
+		if !ok {
+			continue
+		}
+
+		c, err := s.counter(ctx, sub)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Failures++
+		c.LastFailure = now
+
+		ttl := policy.Window
+
+		if policy.BanAfter > 0 && c.Failures >= policy.BanAfter {
+			c.Failures = 0
+			c.BannedUntil = now.Add(policy.BanDuration)
+
+			if policy.BanDuration > ttl {
+				ttl = policy.BanDuration
+			}
+
+			if err := s.addBan(ctx, models.AuthBan{Kind: sub.kind, Value: sub.value, Until: c.BannedUntil}); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.cache.Set(ctx, sub.key(), c, ttl); err != nil {
+			return nil, err
+		}
+
+		s.apply(limit, sub, c)
+	}
+
+	return limit, nil
+}
+
+// ListBans returns the subjects currently banned, sorted by expiration.
+func (s *service) ListBans(ctx context.Context) ([]models.AuthBan, error) {
+	bans, err := s.bans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.AuthBan, 0, len(bans))
+	for _, ban := range bans {
+		list = append(list, ban)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Until.Before(list[j].Until)
+	})
+
+	return list, nil
+}
+
+// DeleteBan lifts the ban of the subject, also forgetting its failures.
+func (s *service) DeleteBan(ctx context.Context, kind, value string) error {
+	bans, err := s.bans(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub := subject{kind, value}
+	if _, ok := bans[sub.key()]; !ok {
+		return ErrBanNotFound
+	}
+
+	delete(bans, sub.key())
+
+	if err := s.cache.Delete(ctx, sub.key()); err != nil {
+		return err
+	}
+
+	return s.saveBans(ctx, bans)
+}
+
+// apply raises the limit to the delay and the ban of the subject.
+func (s *service) apply(limit *models.AuthLimit, sub subject, c *counter) {
+	now := clock.Now()
+
+	if c.BannedUntil.After(now) && (limit.BannedUntil == nil || c.BannedUntil.After(*limit.BannedUntil)) {
+		until := c.BannedUntil
+		limit.BannedUntil = &until
+	}
+
+	policy, ok := s.policies[sub.kind]
+	if !ok || c.Failures < policy.Threshold {
+		return
+	}
+
+	delay := policy.Delay
+	for i := policy.Threshold; i < c.Failures && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	if seconds := int(delay.Seconds()); seconds > limit.Delay {
+		limit.Delay = seconds
+	}
+}
+
+func (s *service) counter(ctx context.Context, sub subject) (*counter, error) {
+	c := &counter{}
+	if err := s.cache.Get(ctx, sub.key(), c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// bans returns the index of the current bans, since the cache can not list
+// its keys.
+func (s *service) bans(ctx context.Context) (map[string]models.AuthBan, error) {
+	bans := make(map[string]models.AuthBan)
+	if err := s.cache.Get(ctx, bansKey, &bans); err != nil {
+		return nil, err
+	}
+
+	// The index is missing or empty when nothing is banned.
+	if bans == nil {
+		bans = make(map[string]models.AuthBan)
+	}
+
+	now := clock.Now()
+	for key, ban := range bans {
+		if !ban.Until.After(now) {
+			delete(bans, key)
+		}
+	}
+
+	return bans, nil
+}
+
+func (s *service) addBan(ctx context.Context, ban models.AuthBan) error {
+	bans, err := s.bans(ctx)
+	if err != nil {
+		return err
+	}
+
+	bans[subject{ban.Kind, ban.Value}.key()] = ban
+
+	return s.saveBans(ctx, bans)
+}
+
+// saveBans saves the index of the bans until the last of them expires.
+func (s *service) saveBans(ctx context.Context, bans map[string]models.AuthBan) error {
+	if len(bans) == 0 {
+		return s.cache.Delete(ctx, bansKey)
+	}
+
+	var ttl time.Duration
+
+	now := clock.Now()
+	for _, ban := range bans {
+		if d := ban.Until.Sub(now); d > ttl {
+			ttl = d
+		}
+	}
+
+	return s.cache.Set(ctx, bansKey, bans, ttl)
+}
@@ -0,0 +1,128 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	storecache "github.com/shellhub-io/shellhub/api/store/cache"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	clockmocks "github.com/shellhub-io/shellhub/pkg/clock/mocks"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var policies = map[string]Policy{
+	models.AuthLimitIP: {
+		Threshold:   2,
+		Delay:       time.Second,
+		MaxDelay:    4 * time.Second,
+		BanAfter:    6,
+		BanDuration: time.Hour,
+		Window:      time.Hour,
+	},
+}
+
+func TestFailure(t *testing.T) {
+	now := time.Now()
+
+	clockMock := &clockmocks.Clock{}
+	clock.DefaultBackend = clockMock
+	clockMock.On("Now").Return(func() time.Time { return now })
+
+	ctx := context.TODO()
+	s := NewServiceWithPolicies(storecache.NewMemoryCache(), policies)
+
+	attempt := &models.AuthAttempt{IPAddress: "192.168.1.1", Username: "root", Device: "uid"}
+
+	// The delay starts over the threshold and is doubled up to the maximum.
+	for _, expected := range []int{0, 1, 2, 4, 4} {
+		limit, err := s.Failure(ctx, attempt)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, limit.Delay)
+		assert.Nil(t, limit.BannedUntil)
+	}
+
+	limit, err := s.Check(ctx, attempt)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, limit.Delay)
+
+	// The subjects without a policy are not limited.
+	limit, err = s.Check(ctx, &models.AuthAttempt{IPAddress: "192.168.1.2"})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.AuthLimit{}, limit)
+
+	limit, err = s.Failure(ctx, attempt)
+	assert.NoError(t, err)
+	assert.NotNil(t, limit.BannedUntil)
+	assert.Equal(t, now.Add(time.Hour), *limit.BannedUntil)
+
+	bans, err := s.ListBans(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, bans, 1)
+	assert.Equal(t, models.AuthLimitIP, bans[0].Kind)
+	assert.Equal(t, attempt.IPAddress, bans[0].Value)
+
+	// The ban expires with its duration.
+	now = now.Add(time.Hour)
+
+	limit, err = s.Check(ctx, attempt)
+	assert.NoError(t, err)
+	assert.Nil(t, limit.BannedUntil)
+
+	bans, err = s.ListBans(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, bans)
+}
+
+func TestDeleteBan(t *testing.T) {
+	ctx := context.TODO()
+	s := NewServiceWithPolicies(storecache.NewMemoryCache(), policies)
+
+	attempt := &models.AuthAttempt{IPAddress: "192.168.1.1"}
+
+	for i := 0; i < policies[models.AuthLimitIP].BanAfter; i++ {
+		_, err := s.Failure(ctx, attempt)
+		assert.NoError(t, err)
+	}
+
+	limit, err := s.Check(ctx, attempt)
+	assert.NoError(t, err)
+	assert.NotNil(t, limit.BannedUntil)
+
+	assert.Equal(t, ErrBanNotFound, s.DeleteBan(ctx, models.AuthLimitIP, "192.168.1.2"))
+	assert.NoError(t, s.DeleteBan(ctx, models.AuthLimitIP, attempt.IPAddress))
+
+	limit, err = s.Check(ctx, attempt)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.AuthLimit{}, limit)
+
+	bans, err := s.ListBans(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, bans)
+}
+
+func TestDefaultPoliciesBanOnlyIP(t *testing.T) {
+	ctx := context.TODO()
+	s := NewService(storecache.NewMemoryCache())
+
+	// The failures come from many addresses, so only the user and the device
+	// accumulate them.
+	for i := 0; i < 200; i++ {
+		attempt := &models.AuthAttempt{IPAddress: fmt.Sprintf("10.0.%d.%d", i/256, i%256), Username: "root", Device: "uid"}
+
+		limit, err := s.Failure(ctx, attempt)
+		assert.NoError(t, err)
+		assert.Nil(t, limit.BannedUntil)
+	}
+
+	limit, err := s.Check(ctx, &models.AuthAttempt{Username: "root", Device: "uid"})
+	assert.NoError(t, err)
+	assert.Nil(t, limit.BannedUntil)
+	assert.Equal(t, 30, limit.Delay)
+
+	bans, err := s.ListBans(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, bans)
+}
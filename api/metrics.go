@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MetricsURL is where the API serves its Prometheus metrics. It is not routed
+// by the API gateway, so it is only reachable within the container network.
+const MetricsURL = "/metrics"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "shellhub",
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "shellhub",
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "shellhub",
+		Subsystem: "api",
+		Name:      "mongo_command_duration_seconds",
+		Help:      "Latency of the MongoDB commands by command name and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "result"})
+)
+
+// metricsMiddleware records the count and latency of the requests. The route
+// is the registered path (e.g. /api/devices/:uid), so the cardinality does not
+// grow with the parameters of the requests.
+func metricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		err := next(c)
+		if err != nil {
+			// The error handler writes the response, so the status code
+			// is only known after it runs.
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		method := c.Request().Method
+
+		requestsTotal.WithLabelValues(route, method, strconv.Itoa(c.Response().Status)).Inc()
+		requestDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
+
+// mongoMonitor records the latency of the commands sent to MongoDB.
+func mongoMonitor() *event.CommandMonitor {
+	observe := func(e event.CommandFinishedEvent, result string) {
+		mongoCommandDuration.WithLabelValues(e.CommandName, result).Observe(time.Duration(e.DurationNanos).Seconds())
+	}
+
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observe(e.CommandFinishedEvent, "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observe(e.CommandFinishedEvent, "failure")
+		},
+	}
+}
@@ -26,6 +26,12 @@ type Service interface {
 	ListMembers(ctx context.Context, tenantID string) ([]models.Member, error)
 	EditSessionRecordStatus(ctx context.Context, status bool, tenant, ownerID string) error
 	GetSessionRecord(ctx context.Context, tenant string) (bool, error)
+	EditPortForwardingStatus(ctx context.Context, status bool, tenant, ownerID string) error
+	GetPortForwarding(ctx context.Context, tenant string) (bool, error)
+	EditAgentForwardingStatus(ctx context.Context, status bool, tenant, ownerID string) error
+	GetAgentForwarding(ctx context.Context, tenant string) (bool, error)
+	EditMFAStatus(ctx context.Context, status bool, tenant, ownerID string) error
+	GetMFA(ctx context.Context, tenant string) (bool, error)
 }
 
 type service struct {
@@ -65,7 +71,7 @@ func (s *service) CreateNamespace(ctx context.Context, namespace *models.Namespa
 		Name:     strings.ToLower(namespace.Name),
 		Owner:    user.ID,
 		Members:  []interface{}{user.ID},
-		Settings: &models.NamespaceSettings{SessionRecord: true},
+		Settings: &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 		TenantID: namespace.TenantID,
 	}
 
@@ -219,3 +225,63 @@ func (s *service) GetSessionRecord(ctx context.Context, tenant string) (bool, er
 
 	return s.store.NamespaceGetSessionRecord(ctx, tenant)
 }
+
+func (s *service) EditPortForwardingStatus(ctx context.Context, portForwarding bool, tenant, ownerID string) error {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return err
+	}
+
+	return s.store.NamespaceSetPortForwarding(ctx, portForwarding, tenant)
+}
+
+func (s *service) GetPortForwarding(ctx context.Context, tenant string) (bool, error) {
+	if _, err := s.store.NamespaceGet(ctx, tenant); err != nil {
+		if err == store.ErrNoDocuments {
+			return false, ErrNamespaceNotFound
+		}
+
+		return false, err
+	}
+
+	return s.store.NamespaceGetPortForwarding(ctx, tenant)
+}
+
+func (s *service) EditAgentForwardingStatus(ctx context.Context, agentForwarding bool, tenant, ownerID string) error {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return err
+	}
+
+	return s.store.NamespaceSetAgentForwarding(ctx, agentForwarding, tenant)
+}
+
+func (s *service) GetAgentForwarding(ctx context.Context, tenant string) (bool, error) {
+	if _, err := s.store.NamespaceGet(ctx, tenant); err != nil {
+		if err == store.ErrNoDocuments {
+			return false, ErrNamespaceNotFound
+		}
+
+		return false, err
+	}
+
+	return s.store.NamespaceGetAgentForwarding(ctx, tenant)
+}
+
+func (s *service) EditMFAStatus(ctx context.Context, mfa bool, tenant, ownerID string) error {
+	if err := utils.IsNamespaceOwner(ctx, s.store, tenant, ownerID); err != nil {
+		return err
+	}
+
+	return s.store.NamespaceSetMFA(ctx, mfa, tenant)
+}
+
+func (s *service) GetMFA(ctx context.Context, tenant string) (bool, error) {
+	if _, err := s.store.NamespaceGet(ctx, tenant); err != nil {
+		if err == store.ErrNoDocuments {
+			return false, ErrNamespaceNotFound
+		}
+
+		return false, err
+	}
+
+	return s.store.NamespaceGetMFA(ctx, tenant)
+}
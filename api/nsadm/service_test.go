@@ -374,7 +374,7 @@ func TestCreateNamespace(t *testing.T) {
 		Name:     strings.ToLower("namespace"),
 		Owner:    user.ID,
 		Members:  []interface{}{user.ID},
-		Settings: &models.NamespaceSettings{SessionRecord: true},
+		Settings: &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 		TenantID: "xxxxx",
 	}
 
@@ -463,7 +463,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower("namespace"),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   "xxxxx",
 					MaxDevices: -1,
 				}
@@ -483,7 +483,7 @@ func TestCreateNamespace(t *testing.T) {
 				Name:       strings.ToLower("namespace"),
 				Owner:      user.ID,
 				Members:    []interface{}{user.ID},
-				Settings:   &models.NamespaceSettings{SessionRecord: true},
+				Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 				TenantID:   "",
 				MaxDevices: -1,
 			},
@@ -493,7 +493,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower("namespace"),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   "random_uuid",
 					MaxDevices: -1,
 				}
@@ -508,7 +508,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower("namespace"),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   "random_uuid",
 					MaxDevices: -1,
 				}, nil,
@@ -524,7 +524,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower("namespace"),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   "xxxxx",
 					MaxDevices: -1,
 				}
@@ -538,7 +538,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower(namespace.Name),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   namespace.TenantID,
 					MaxDevices: -1,
 				}, nil,
@@ -554,7 +554,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower("namespace"),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   "xxxxx",
 					MaxDevices: 3,
 				}
@@ -568,7 +568,7 @@ func TestCreateNamespace(t *testing.T) {
 					Name:       strings.ToLower(namespace.Name),
 					Owner:      user.ID,
 					Members:    []interface{}{user.ID},
-					Settings:   &models.NamespaceSettings{SessionRecord: true},
+					Settings:   &models.NamespaceSettings{SessionRecord: true, AgentForwarding: true},
 					TenantID:   namespace.TenantID,
 					MaxDevices: 3,
 				}, nil,
@@ -1138,3 +1138,405 @@ func TestEditSessionRecord(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestGetPortForwarding(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	Err := errors.New("error")
+
+	type Expected struct {
+		status bool
+		err    error
+	}
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "a736a52b-5777-4f92-b0b8-e359bf484713", Settings: &models.NamespaceSettings{PortForwarding: true}}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		tenantID      string
+		expected      Expected
+	}{
+		{
+			name: "GetPortForwarding fails when the namespace document is not found",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, store.ErrNoDocuments).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, ErrNamespaceNotFound},
+		},
+		{
+			name: "GetPortForwarding fails when store namespace get fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(nil, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetPortForwarding fails when store namespace get port forwarding fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetPortForwarding", ctx, namespace.TenantID).Return(false, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetPortForwarding succeeds",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetPortForwarding", ctx, namespace.TenantID).Return(true, nil).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{true, nil},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			status, err := s.GetPortForwarding(ctx, tc.tenantID)
+			assert.Equal(t, tc.expected, Expected{status, err})
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestEditPortForwarding(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "xxxx", Settings: &models.NamespaceSettings{PortForwarding: false}}
+	user := &models.User{Name: "user1", Username: "username1", ID: "hash1"}
+	user2 := &models.User{Name: "user2", Username: "username2", ID: "hash2"}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name              string
+		requiredMocks     func()
+		portForwarding    bool
+		ownerID, tenantID string
+		expected          error
+	}{
+		{
+			name:     "EditPortForwarding fails when user is not the owner",
+			ownerID:  user2.ID,
+			tenantID: namespace.TenantID,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, user2.ID, false).Return(user2, 0, nil).Once()
+			},
+			expected: ErrUnauthorized,
+		},
+		{
+			name:    "EditPortForwarding fails when namespace set port forwarding fails",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetPortForwarding", ctx, true, namespace.TenantID).Return(Err).Once()
+			},
+			tenantID:       namespace.TenantID,
+			portForwarding: true,
+			expected:       Err,
+		},
+		{
+			name:    "EditPortForwarding succeeds",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetPortForwarding", ctx, true, namespace.TenantID).Return(nil).Once()
+			},
+			tenantID:       namespace.TenantID,
+			portForwarding: true,
+			expected:       nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.EditPortForwardingStatus(ctx, tc.portForwarding, tc.tenantID, tc.ownerID)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestGetAgentForwarding(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	Err := errors.New("error")
+
+	type Expected struct {
+		status bool
+		err    error
+	}
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "a736a52b-5777-4f92-b0b8-e359bf484713", Settings: &models.NamespaceSettings{AgentForwarding: true}}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		tenantID      string
+		expected      Expected
+	}{
+		{
+			name: "GetAgentForwarding fails when the namespace document is not found",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, store.ErrNoDocuments).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, ErrNamespaceNotFound},
+		},
+		{
+			name: "GetAgentForwarding fails when store namespace get fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(nil, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetAgentForwarding fails when store namespace get agent forwarding fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetAgentForwarding", ctx, namespace.TenantID).Return(false, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetAgentForwarding succeeds",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetAgentForwarding", ctx, namespace.TenantID).Return(true, nil).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{true, nil},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			status, err := s.GetAgentForwarding(ctx, tc.tenantID)
+			assert.Equal(t, tc.expected, Expected{status, err})
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestEditAgentForwarding(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "xxxx", Settings: &models.NamespaceSettings{AgentForwarding: true}}
+	user := &models.User{Name: "user1", Username: "username1", ID: "hash1"}
+	user2 := &models.User{Name: "user2", Username: "username2", ID: "hash2"}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name              string
+		requiredMocks     func()
+		agentForwarding   bool
+		ownerID, tenantID string
+		expected          error
+	}{
+		{
+			name:     "EditAgentForwarding fails when user is not the owner",
+			ownerID:  user2.ID,
+			tenantID: namespace.TenantID,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, user2.ID, false).Return(user2, 0, nil).Once()
+			},
+			expected: ErrUnauthorized,
+		},
+		{
+			name:    "EditAgentForwarding fails when namespace set agent forwarding fails",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetAgentForwarding", ctx, false, namespace.TenantID).Return(Err).Once()
+			},
+			tenantID:        namespace.TenantID,
+			agentForwarding: false,
+			expected:        Err,
+		},
+		{
+			name:    "EditAgentForwarding succeeds",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetAgentForwarding", ctx, false, namespace.TenantID).Return(nil).Once()
+			},
+			tenantID:        namespace.TenantID,
+			agentForwarding: false,
+			expected:        nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.EditAgentForwardingStatus(ctx, tc.agentForwarding, tc.tenantID, tc.ownerID)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestGetMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	Err := errors.New("error")
+
+	type Expected struct {
+		status bool
+		err    error
+	}
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "a736a52b-5777-4f92-b0b8-e359bf484713", Settings: &models.NamespaceSettings{MFA: true}}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		tenantID      string
+		expected      Expected
+	}{
+		{
+			name: "GetMFA fails when the namespace document is not found",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, store.ErrNoDocuments).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, ErrNamespaceNotFound},
+		},
+		{
+			name: "GetMFA fails when store namespace get fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(nil, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetMFA fails when store namespace get MFA fails",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetMFA", ctx, namespace.TenantID).Return(false, Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{false, Err},
+		},
+		{
+			name: "GetMFA succeeds",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("NamespaceGetMFA", ctx, namespace.TenantID).Return(true, nil).Once()
+			},
+			tenantID: namespace.TenantID,
+			expected: Expected{true, nil},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			status, err := s.GetMFA(ctx, tc.tenantID)
+			assert.Equal(t, tc.expected, Expected{status, err})
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestEditMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	namespace := &models.Namespace{Name: "group1", Owner: "hash1", TenantID: "xxxx", Settings: &models.NamespaceSettings{MFA: true}}
+	user := &models.User{Name: "user1", Username: "username1", ID: "hash1"}
+	user2 := &models.User{Name: "user2", Username: "username2", ID: "hash2"}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name              string
+		requiredMocks     func()
+		mfa               bool
+		ownerID, tenantID string
+		expected          error
+	}{
+		{
+			name:     "EditMFA fails when user is not the owner",
+			ownerID:  user2.ID,
+			tenantID: namespace.TenantID,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, user2.ID, false).Return(user2, 0, nil).Once()
+			},
+			expected: ErrUnauthorized,
+		},
+		{
+			name:    "EditMFA fails when namespace set MFA fails",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetMFA", ctx, false, namespace.TenantID).Return(Err).Once()
+			},
+			tenantID: namespace.TenantID,
+			mfa:      false,
+			expected: Err,
+		},
+		{
+			name:    "EditMFA succeeds",
+			ownerID: namespace.Owner,
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByID", ctx, namespace.Owner, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceSetMFA", ctx, false, namespace.TenantID).Return(nil).Once()
+			},
+			tenantID: namespace.TenantID,
+			mfa:      false,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.EditMFAStatus(ctx, tc.mfa, tc.tenantID, tc.ownerID)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
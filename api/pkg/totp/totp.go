@@ -0,0 +1,110 @@
+// Package totp implements the time-based one-time passwords of RFC 6238, as
+// generated by the common authenticator apps (30 seconds steps, 6 digits and
+// HMAC-SHA1).
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the time in seconds each code is valid for.
+	Period = 30
+	// Digits is the number of digits of the codes.
+	Digits = 6
+	// Skew is the number of steps before and after the current one whose
+	// codes are also accepted, to tolerate clock drifts.
+	Skew = 1
+
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random secret encoded in base32.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Code returns the code of the secret at the time t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return code(key, uint64(t.Unix()/Period)), nil
+}
+
+// Validate reports whether the code is valid for the secret at the time t.
+func Validate(secret, passcode string, t time.Time) bool {
+	_, ok := ValidateStep(secret, passcode, t)
+
+	return ok
+}
+
+// ValidateStep reports whether the code is valid for the secret at the time t,
+// returning the time step it was generated for. The step of the last accepted
+// code is kept by the verifiers to reject it when it is replayed (RFC 6238,
+// Section 5.2).
+func ValidateStep(secret, passcode string, t time.Time) (int64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(passcode) != Digits {
+		return 0, false
+	}
+
+	step := t.Unix() / Period
+	for i := int64(-Skew); i <= Skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(code(key, uint64(step+i))), []byte(passcode)) == 1 {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// URL returns the otpauth URL of the secret, which is usually shown as a QR
+// code to be scanned by the authenticator apps.
+func URL(issuer, account, secret string) string {
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + account,
+		RawQuery: url.Values{
+			"secret": {secret},
+			"issuer": {issuer},
+		}.Encode(),
+	}
+
+	return u.String()
+}
+
+// code computes the HOTP value of RFC 4226 for the counter.
+func code(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg) // nolint:errcheck
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%uint32(math.Pow10(Digits)))
+}
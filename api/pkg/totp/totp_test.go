@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// secret is the SHA1 secret of the test vectors of RFC 6238.
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	cases := []struct {
+		time     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range cases {
+		code, err := Code(secret, time.Unix(tc.time, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	cases := []struct {
+		name     string
+		secret   string
+		code     string
+		time     time.Time
+		expected bool
+	}{
+		{"Validate accepts the code of the current step", secret, "050471", now, true},
+		{"Validate accepts the code of the previous step", secret, "050471", now.Add(Period * time.Second), true},
+		{"Validate rejects the code of an older step", secret, "050471", now.Add(2 * Period * time.Second), false},
+		{"Validate rejects a wrong code", secret, "123456", now, false},
+		{"Validate rejects a code of the wrong length", secret, "50471", now, false},
+		{"Validate rejects an invalid secret", "invalid!", "050471", now, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Validate(tc.secret, tc.code, tc.time))
+		})
+	}
+}
+
+func TestValidateStep(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := ValidateStep(secret, "050471", now.Add(Period*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, step)
+
+	_, ok = ValidateStep(secret, "123456", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+
+	code, err := Code(secret, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, Validate(secret, code, time.Now()))
+}
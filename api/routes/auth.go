@@ -21,6 +21,8 @@ const (
 	AuthUserURLV2    = "/auth/user"
 	AuthUserTokenURL = "/auth/token/:tenant" //nolint:gosec
 	AuthPublicKeyURL = "/auth/ssh"
+	// AuthBreakGlassKeysURL is requested by the agent with the device token
+	AuthBreakGlassKeysURL = "/auth/ssh/keys"
 )
 
 func AuthRequest(c apicontext.Context) error {
@@ -154,6 +156,24 @@ func AuthPublicKey(c apicontext.Context) error {
 	return c.JSON(http.StatusOK, res)
 }
 
+// AuthBreakGlassKeys returns the signed public keys cached by the agent for
+// its local SSH listener.
+func AuthBreakGlassKeys(c apicontext.Context) error {
+	uid := c.Request().Header.Get(api.DeviceUIDHeader)
+	if uid == "" {
+		return echo.ErrUnauthorized
+	}
+
+	svc := authsvc.NewService(c.Store(), nil, nil)
+
+	res, err := svc.AuthBreakGlassKeys(c.Ctx(), models.UID(uid))
+	if err != nil {
+		return echo.ErrUnauthorized
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
 func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		ctx := c.Get("ctx").(*apicontext.Context)
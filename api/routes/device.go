@@ -4,20 +4,27 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/labstack/echo/v4"
 	"github.com/shellhub-io/shellhub/api/apicontext"
 	"github.com/shellhub-io/shellhub/api/deviceadm"
+	"github.com/shellhub-io/shellhub/api/store"
+	api "github.com/shellhub-io/shellhub/pkg/api/client"
 	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/models"
 )
 
 const (
-	GetDeviceListURL = "/devices"
-	GetDeviceURL     = "/devices/:uid"
-	DeleteDeviceURL  = "/devices/:uid"
-	RenameDeviceURL  = "/devices/:uid"
-	OfflineDeviceURL = "/devices/:uid/offline"
-	LookupDeviceURL  = "/lookup"
-	UpdateStatusURL  = "/devices/:uid/:status"
+	GetDeviceListURL       = "/devices"
+	GetDeviceURL           = "/devices/:uid"
+	DeleteDeviceURL        = "/devices/:uid"
+	RenameDeviceURL        = "/devices/:uid"
+	OfflineDeviceURL       = "/devices/:uid/offline"
+	LookupDeviceURL        = "/lookup"
+	UpdateStatusURL        = "/devices/:uid/:status"
+	GetDeviceContainersURL = "/devices/:uid/containers"
+	// The break-glass logins are reported by the agent with the device token
+	CreateBreakGlassLoginsURL = "/devices/break-glass/logins"
+	GetBreakGlassLoginsURL    = "/devices/:uid/break-glass/logins"
 )
 
 const TenantIDHeader = "X-Tenant-ID"
@@ -61,6 +68,79 @@ func GetDevice(c apicontext.Context) error {
 	return c.JSON(http.StatusOK, device)
 }
 
+// GetDeviceContainers lists the running containers reported by the agent of
+// the device, which can be used as session targets.
+func GetDeviceContainers(c apicontext.Context) error {
+	svc := deviceadm.NewService(c.Store())
+
+	device, err := svc.GetDevice(c.Ctx(), models.UID(c.Param("uid")))
+	if err != nil {
+		return err
+	}
+
+	containers := device.Containers
+	if containers == nil {
+		containers = []models.DeviceContainer{}
+	}
+
+	return c.JSON(http.StatusOK, containers)
+}
+
+// CreateBreakGlassLogins records the logins made through the local SSH
+// listener of the device while the server was unreachable.
+func CreateBreakGlassLogins(c apicontext.Context) error {
+	uid := c.Request().Header.Get(api.DeviceUIDHeader)
+	if uid == "" {
+		return echo.ErrUnauthorized
+	}
+
+	var logins []models.BreakGlassLogin
+	if err := c.Bind(&logins); err != nil {
+		return err
+	}
+
+	svc := deviceadm.NewService(c.Store())
+
+	if err := svc.CreateBreakGlassLogins(c.Ctx(), models.UID(uid), logins); err != nil {
+		if err == store.ErrNoDocuments {
+			return echo.ErrUnauthorized
+		}
+
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func GetBreakGlassLogins(c apicontext.Context) error {
+	svc := deviceadm.NewService(c.Store())
+
+	query := paginator.NewQuery()
+	if err := c.Bind(query); err != nil {
+		return err
+	}
+
+	query.Normalize()
+
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	logins, count, err := svc.ListBreakGlassLogins(c.Ctx(), models.UID(c.Param("uid")), tenant, *query)
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(count))
+
+	return c.JSON(http.StatusOK, logins)
+}
+
 func DeleteDevice(c apicontext.Context) error {
 	svc := deviceadm.NewService(c.Store())
 
@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/jobs"
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+const (
+	GetJobListURL = "/jobs"
+	GetJobURL     = "/jobs/:uid"
+	ExecDeviceURL = "/devices/:uid/exec"
+	ExecFleetURL  = "/devices/exec"
+
+	CollectDiagnosticsURL = "/devices/:uid/diagnostics"
+)
+
+func GetJobList(c apicontext.Context) error {
+	svc := jobs.NewService(c.Store(), jobs.DefaultDispatcher)
+
+	query := paginator.NewQuery()
+	if err := c.Bind(query); err != nil {
+		return err
+	}
+
+	query.Normalize()
+
+	list, count, err := svc.ListJobs(c.Ctx(), *query)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(count))
+
+	return c.JSON(http.StatusOK, list)
+}
+
+func GetJob(c apicontext.Context) error {
+	svc := jobs.NewService(c.Store(), jobs.DefaultDispatcher)
+
+	job, err := svc.GetJob(c.Ctx(), models.UID(c.Param("uid")))
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func ExecDevice(c apicontext.Context) error {
+	var req jobs.Request
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	svc := jobs.NewService(c.Store(), jobs.DefaultDispatcher)
+
+	tenant, id, username := jobCaller(c)
+
+	job, err := svc.ExecDevice(c.Ctx(), models.UID(c.Param("uid")), &req, tenant, id, username)
+	if err != nil {
+		return jobError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func ExecFleet(c apicontext.Context) error {
+	var req jobs.Request
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	svc := jobs.NewService(c.Store(), jobs.DefaultDispatcher)
+
+	tenant, id, username := jobCaller(c)
+
+	job, err := svc.ExecFleet(c.Ctx(), &req, tenant, id, username)
+	if err != nil {
+		return jobError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func CollectDiagnostics(c apicontext.Context) error {
+	svc := jobs.NewService(c.Store(), jobs.DefaultDispatcher)
+
+	tenant, id, username := jobCaller(c)
+
+	uid := c.Param("uid")
+
+	bundle, err := svc.CollectDiagnostics(c.Ctx(), models.UID(uid), tenant, id, username)
+	if err != nil {
+		return jobError(c, err)
+	}
+	defer bundle.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=diagnostics-%s.tar", uid))
+
+	return c.Stream(http.StatusOK, "application/x-tar", bundle)
+}
+
+// jobCaller returns the tenant, the user ID and the username of the user
+// requesting a job.
+func jobCaller(c apicontext.Context) (tenant, id, username string) {
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	if v := c.Username(); v != nil {
+		username = v.ID
+	}
+
+	return tenant, id, username
+}
+
+func jobError(c apicontext.Context, err error) error {
+	switch err {
+	case jobs.ErrUnauthorized:
+		return c.NoContent(http.StatusForbidden)
+	case jobs.ErrInvalidJob:
+		return c.NoContent(http.StatusBadRequest)
+	case jobs.ErrDeviceNotAccepted, jobs.ErrNoDevices, jobs.ErrTooManyDevices:
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	case jobs.ErrDeviceUnreachable:
+		return c.String(http.StatusBadGateway, err.Error())
+	case store.ErrNoDocuments:
+		return c.NoContent(http.StatusNotFound)
+	default:
+		return err
+	}
+}
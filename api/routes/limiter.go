@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/limiter"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+const (
+	CheckAuthLimitURL    = "/auth/limits/check"
+	ReportAuthFailureURL = "/auth/limits/failures"
+	ListAuthBansURL      = "/auth/bans"
+	DeleteAuthBanURL     = "/auth/bans/:kind/:value"
+)
+
+// CheckAuthLimit returns the delay and the ban applied to an authentication
+// attempt on the SSH gateway.
+func CheckAuthLimit(c apicontext.Context) error {
+	var attempt models.AuthAttempt
+	if err := c.Bind(&attempt); err != nil {
+		return err
+	}
+
+	svc := limiter.NewService(c.Cache())
+
+	limit, err := svc.Check(c.Ctx(), &attempt)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, limit)
+}
+
+// ReportAuthFailure counts a failed authentication on the SSH gateway.
+func ReportAuthFailure(c apicontext.Context) error {
+	var attempt models.AuthAttempt
+	if err := c.Bind(&attempt); err != nil {
+		return err
+	}
+
+	svc := limiter.NewService(c.Cache())
+
+	limit, err := svc.Failure(c.Ctx(), &attempt)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, limit)
+}
+
+func ListAuthBans(c apicontext.Context) error {
+	svc := limiter.NewService(c.Cache())
+
+	bans, err := svc.ListBans(c.Ctx())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, bans)
+}
+
+func DeleteAuthBan(c apicontext.Context) error {
+	svc := limiter.NewService(c.Cache())
+
+	if err := svc.DeleteBan(c.Ctx(), c.Param("kind"), c.Param("value")); err != nil {
+		if err == limiter.ErrBanNotFound {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
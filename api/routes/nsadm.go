@@ -1,24 +1,35 @@
 package routes
 
 import (
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/deviceadm"
 	"github.com/shellhub-io/shellhub/api/nsadm"
 	"github.com/shellhub-io/shellhub/pkg/models"
 )
 
 const (
-	ListNamespaceURL           = "/namespaces"
-	CreateNamespaceURL         = "/namespaces"
-	GetNamespaceURL            = "/namespaces/:id"
-	DeleteNamespaceURL         = "/namespaces/:id"
-	EditNamespaceURL           = "/namespaces/:id"
-	AddNamespaceUserURL        = "/namespaces/:id/add"
-	RemoveNamespaceUserURL     = "/namespaces/:id/del"
-	GetSessionRecordURL        = "/users/security"
-	EditSessionRecordStatusURL = "/users/security/:id"
+	ListNamespaceURL             = "/namespaces"
+	CreateNamespaceURL           = "/namespaces"
+	GetNamespaceURL              = "/namespaces/:id"
+	DeleteNamespaceURL           = "/namespaces/:id"
+	EditNamespaceURL             = "/namespaces/:id"
+	AddNamespaceUserURL          = "/namespaces/:id/add"
+	RemoveNamespaceUserURL       = "/namespaces/:id/del"
+	GetSessionRecordURL          = "/users/security"
+	EditSessionRecordStatusURL   = "/users/security/:id"
+	GetPortForwardingURL         = "/users/security/port-forwarding"
+	EditPortForwardingStatusURL  = "/users/security/port-forwarding/:id"
+	GetAgentForwardingURL        = "/users/security/agent-forwarding"
+	EditAgentForwardingStatusURL = "/users/security/agent-forwarding/:id"
+	GetMFAURL                    = "/users/security/mfa"
+	EditMFAStatusURL             = "/users/security/mfa/:id"
+	ExportKnownHostsURL          = "/namespaces/:id/known_hosts"
+	ExportSSHConfigURL           = "/namespaces/:id/ssh_config"
 )
 
 func GetNamespaceList(c apicontext.Context) error {
@@ -268,3 +279,211 @@ func GetSessionRecord(c apicontext.Context) error {
 
 	return c.JSON(http.StatusOK, status)
 }
+
+func EditPortForwardingStatus(c apicontext.Context) error {
+	var req struct {
+		PortForwarding bool `json:"port_forwarding"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	id := ""
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	tenant := c.Param("id")
+
+	svc := nsadm.NewService(c.Store())
+
+	if err := svc.EditPortForwardingStatus(c.Ctx(), req.PortForwarding, tenant, id); err != nil {
+		switch err {
+		case nsadm.ErrUnauthorized:
+			return c.NoContent(http.StatusForbidden)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func GetPortForwarding(c apicontext.Context) error {
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	svc := nsadm.NewService(c.Store())
+
+	status, err := svc.GetPortForwarding(c.Ctx(), tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func EditAgentForwardingStatus(c apicontext.Context) error {
+	var req struct {
+		AgentForwarding bool `json:"agent_forwarding"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	id := ""
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	tenant := c.Param("id")
+
+	svc := nsadm.NewService(c.Store())
+
+	if err := svc.EditAgentForwardingStatus(c.Ctx(), req.AgentForwarding, tenant, id); err != nil {
+		switch err {
+		case nsadm.ErrUnauthorized:
+			return c.NoContent(http.StatusForbidden)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func GetAgentForwarding(c apicontext.Context) error {
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	svc := nsadm.NewService(c.Store())
+
+	status, err := svc.GetAgentForwarding(c.Ctx(), tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func EditMFAStatus(c apicontext.Context) error {
+	var req struct {
+		MFA bool `json:"mfa"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	id := ""
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	tenant := c.Param("id")
+
+	svc := nsadm.NewService(c.Store())
+
+	if err := svc.EditMFAStatus(c.Ctx(), req.MFA, tenant, id); err != nil {
+		switch err {
+		case nsadm.ErrUnauthorized:
+			return c.NoContent(http.StatusForbidden)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func GetMFA(c apicontext.Context) error {
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	svc := nsadm.NewService(c.Store())
+
+	status, err := svc.GetMFA(c.Ctx(), tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// ExportKnownHosts returns the known_hosts file with the host keys of the
+// devices of the namespace.
+func ExportKnownHosts(c apicontext.Context) error {
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	if tenant == "" || c.Param("id") != tenant {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	svc := deviceadm.NewService(c.Store())
+
+	data, err := svc.ExportKnownHosts(c.Ctx(), tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.Blob(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// ExportSSHConfig returns the ssh_config file which reaches the devices of
+// the namespace through the gateway used as a jump host. The gateway address
+// defaults to the host of the request and the SSH port of the server.
+func ExportSSHConfig(c apicontext.Context) error {
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	if tenant == "" || c.Param("id") != tenant {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	gateway := c.QueryParam("gateway")
+	if gateway == "" {
+		gateway = sshGateway(c)
+	}
+
+	svc := deviceadm.NewService(c.Store())
+
+	data, err := svc.ExportSSHConfig(c.Ctx(), tenant, gateway)
+	if err != nil {
+		if err == deviceadm.ErrInvalidGateway {
+			return c.NoContent(http.StatusBadRequest)
+		}
+
+		return err
+	}
+
+	return c.Blob(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// sshGateway returns the address of the SSH server of the gateway, as reached
+// by the client of the request.
+func sshGateway(c apicontext.Context) string {
+	host := c.Request().Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = c.Request().Host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if port := os.Getenv("SHELLHUB_SSH_PORT"); port != "" && port != "22" {
+		return net.JoinHostPort(host, port)
+	}
+
+	return host
+}
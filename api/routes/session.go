@@ -6,6 +6,7 @@ import (
 
 	"github.com/shellhub-io/shellhub/api/apicontext"
 	"github.com/shellhub-io/shellhub/api/sessionmngr"
+	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/models"
 )
@@ -18,6 +19,8 @@ const (
 	FinishSessionURL           = "/sessions/:uid/finish"
 	RecordSessionURL           = "/sessions/:uid/record"
 	PlaySessionURL             = "/sessions/:uid/play"
+	CreateSessionForwardURL    = "/sessions/:uid/forwards"
+	SetSessionStateURL         = "/sessions/:uid/state"
 )
 
 func GetSessionList(c apicontext.Context) error {
@@ -66,6 +69,31 @@ func SetSessionAuthenticated(c apicontext.Context) error {
 	return svc.SetSessionAuthenticated(c.Ctx(), models.UID(c.Param("uid")), req.Authenticated)
 }
 
+func SetSessionState(c apicontext.Context) error {
+	var req struct {
+		State string `json:"state"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	svc := sessionmngr.NewService(c.Store())
+
+	if err := svc.SetSessionState(c.Ctx(), models.UID(c.Param("uid")), req.State); err != nil {
+		switch err {
+		case sessionmngr.ErrInvalidSessionState:
+			return c.NoContent(http.StatusBadRequest)
+		case store.ErrNoDocuments:
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
 func CreateSession(c apicontext.Context) error {
 	session := new(models.Session)
 
@@ -89,6 +117,29 @@ func FinishSession(c apicontext.Context) error {
 	return svc.DeactivateSession(c.Ctx(), models.UID(c.Param("uid")))
 }
 
+func CreateSessionForward(c apicontext.Context) error {
+	var forward models.SessionForward
+
+	if err := c.Bind(&forward); err != nil {
+		return err
+	}
+
+	svc := sessionmngr.NewService(c.Store())
+
+	if err := svc.CreateSessionForward(c.Ctx(), models.UID(c.Param("uid")), &forward); err != nil {
+		switch err {
+		case sessionmngr.ErrInvalidForward:
+			return c.NoContent(http.StatusBadRequest)
+		case sessionmngr.ErrPortForwardingDisabled:
+			return c.NoContent(http.StatusForbidden)
+		default:
+			return err
+		}
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 func RecordSession(c apicontext.Context) error {
 	return c.JSON(http.StatusOK, nil)
 }
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
@@ -16,11 +17,17 @@ import (
 const (
 	GetPublicKeysURL    = "/sshkeys/public-keys"
 	GetPublicKeyURL     = "/sshkeys/public-keys/:fingerprint/:tenant"
+	LookupPublicKeyURL  = "/sshkeys/public-keys/:fingerprint"
 	CreatePublicKeyURL  = "/sshkeys/public-keys"
 	UpdatePublicKeyURL  = "/sshkeys/public-keys/:fingerprint"
 	DeletePublicKeyURL  = "/sshkeys/public-keys/:fingerprint"
 	CreatePrivateKeyURL = "/sshkeys/private-keys"
 	EvaluateKeyURL      = "/sshkeys/public-keys/evaluate/:fingerprint"
+
+	GetCertificateAuthorityURL         = "/sshkeys/certificate-authority"
+	InternalGetCertificateAuthorityURL = "/sshkeys/certificate-authority/:tenant"
+	LookupCertificateAuthorityURL      = "/sshkeys/certificate-authorities/:fingerprint"
+	CreateCertificateURL               = "/sshkeys/certificates"
 )
 
 func GetPublicKeys(c apicontext.Context) error {
@@ -47,7 +54,7 @@ func GetPublicKeys(c apicontext.Context) error {
 func GetPublicKey(c apicontext.Context) error {
 	svc := sshkeys.NewService(c.Store())
 
-	pubKey, err := svc.GetPublicKey(c.Ctx(), c.Param("fingerprint"), c.Param("tenant"))
+	pubKey, err := svc.GetPublicKey(c.Ctx(), fingerprintParam(c), c.Param("tenant"))
 	if err != nil {
 		if err == store.ErrNoDocuments {
 			return c.NoContent(http.StatusNotFound)
@@ -98,7 +105,7 @@ func UpdatePublicKey(c apicontext.Context) error {
 		tenant = v.ID
 	}
 
-	key, err := svc.UpdatePublicKey(c.Ctx(), c.Param("fingerprint"), tenant, &params)
+	key, err := svc.UpdatePublicKey(c.Ctx(), fingerprintParam(c), tenant, &params)
 	if err != nil {
 		return err
 	}
@@ -114,7 +121,7 @@ func DeletePublicKey(c apicontext.Context) error {
 		tenant = v.ID
 	}
 
-	if err := svc.DeletePublicKey(c.Ctx(), c.Param("fingerprint"), tenant); err != nil {
+	if err := svc.DeletePublicKey(c.Ctx(), fingerprintParam(c), tenant); err != nil {
 		return err
 	}
 
@@ -132,10 +139,88 @@ func CreatePrivateKey(c apicontext.Context) error {
 	return c.JSON(http.StatusOK, privKey)
 }
 
+// GetCertificateAuthority returns the certificate authority of the namespace,
+// whose public key is trusted by the gateway to authenticate the certificates.
+func GetCertificateAuthority(c apicontext.Context) error {
+	tenant := c.Param("tenant")
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	svc := sshkeys.NewService(c.Store())
+
+	ca, err := svc.GetCertificateAuthority(c.Ctx(), tenant)
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	return c.JSON(http.StatusOK, ca)
+}
+
+// LookupCertificateAuthority returns the certificate authority whose public key
+// has the fingerprint.
+func LookupCertificateAuthority(c apicontext.Context) error {
+	svc := sshkeys.NewService(c.Store())
+
+	ca, err := svc.GetCertificateAuthorityByFingerprint(c.Ctx(), fingerprintParam(c))
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	return c.JSON(http.StatusOK, ca)
+}
+
+// CreateCertificate issues a user certificate for the public key of the
+// request, signed by the certificate authority of the namespace.
+func CreateCertificate(c apicontext.Context) error {
+	var req models.CertificateRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	tenant := ""
+	if v := c.Tenant(); v != nil {
+		tenant = v.ID
+	}
+
+	id := ""
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	svc := sshkeys.NewService(c.Store())
+
+	cert, err := svc.CreateCertificate(c.Ctx(), tenant, id, &req)
+	if err != nil {
+		switch err {
+		case sshkeys.ErrUnauthorized:
+			return c.NoContent(http.StatusForbidden)
+		case sshkeys.ErrInvalidFormat:
+			return c.NoContent(http.StatusUnprocessableEntity)
+		case sshkeys.ErrInvalidCertificateRequest:
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case store.ErrNoDocuments:
+			return c.NoContent(http.StatusNotFound)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, cert)
+}
+
 func EvaluateKeyHostname(c apicontext.Context) error {
 	svc := sshkeys.NewService(c.Store())
 
-	pubKey, err := svc.GetPublicKey(c.Ctx(), c.Param("fingerprint"), c.Param("tenant"))
+	pubKey, err := svc.GetPublicKey(c.Ctx(), fingerprintParam(c), c.Param("tenant"))
 	if err != nil {
 		return c.JSON(http.StatusForbidden, err)
 	}
@@ -152,3 +237,14 @@ func EvaluateKeyHostname(c apicontext.Context) error {
 
 	return c.JSON(http.StatusOK, ok)
 }
+
+// fingerprintParam returns the fingerprint path parameter. The SHA256
+// fingerprints may contain slashes, so they are escaped by the clients.
+func fingerprintParam(c apicontext.Context) string {
+	fingerprint, err := url.PathUnescape(c.Param("fingerprint"))
+	if err != nil {
+		return c.Param("fingerprint")
+	}
+
+	return fingerprint
+}
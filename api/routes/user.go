@@ -13,6 +13,10 @@ import (
 const (
 	UpdateUserDataURL     = "/users/:id/data"
 	UpdateUserPasswordURL = "/users/:id/password" //nolint:gosec
+	EnrollMFAURL          = "/users/:id/mfa"
+	EnableMFAURL          = "/users/:id/mfa/enable"
+	DisableMFAURL         = "/users/:id/mfa/disable"
+	AuthMFAURL            = "/mfa/auth"
 )
 
 func UpdateUserData(c apicontext.Context) error {
@@ -76,3 +80,106 @@ func UpdateUserPassword(c apicontext.Context) error {
 
 	return c.JSON(http.StatusOK, nil)
 }
+
+// mfaUser returns the user ID of the MFA routes, which may only be managed by
+// the user itself.
+func mfaUser(c apicontext.Context) (string, bool) {
+	id := ""
+	if v := c.ID(); v != nil {
+		id = v.ID
+	}
+
+	return id, id != "" && id == c.Param("id")
+}
+
+func EnrollMFA(c apicontext.Context) error {
+	id, ok := mfaUser(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	svc := user.NewService(c.Store())
+
+	secret, url, err := svc.EnrollMFA(c.Ctx(), id)
+	if err != nil {
+		switch {
+		case err == user.ErrConflict:
+			return c.NoContent(http.StatusConflict)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"secret": secret,
+		"url":    url,
+	})
+}
+
+func EnableMFA(c apicontext.Context) error {
+	return editMFA(c, true)
+}
+
+func DisableMFA(c apicontext.Context) error {
+	return editMFA(c, false)
+}
+
+func editMFA(c apicontext.Context, enable bool) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	id, ok := mfaUser(c)
+	if !ok {
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	svc := user.NewService(c.Store())
+
+	var err error
+	if enable {
+		err = svc.EnableMFA(c.Ctx(), id, req.Code)
+	} else {
+		err = svc.DisableMFA(c.Ctx(), id, req.Code)
+	}
+
+	if err != nil {
+		switch {
+		case err == user.ErrBadRequest:
+			return c.NoContent(http.StatusBadRequest)
+		case err == user.ErrInvalidCode:
+			return c.NoContent(http.StatusForbidden)
+		default:
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+func AuthMFA(c apicontext.Context) error {
+	var req struct {
+		TenantID string `json:"tenant_id"`
+		Username string `json:"username"`
+		Code     string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	svc := user.NewService(c.Store())
+
+	if err := svc.AuthMFA(c.Ctx(), req.TenantID, req.Username, req.Code); err != nil {
+		switch {
+		case err == user.ErrUnauthorized:
+			return c.NoContent(http.StatusUnauthorized)
+		default:
+			return err
+		}
+	}
+
+	return c.NoContent(http.StatusOK)
+}
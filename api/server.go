@@ -6,6 +6,7 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/shellhub-io/shellhub/api/apicontext"
 	"github.com/shellhub-io/shellhub/api/routes"
 	"github.com/shellhub-io/shellhub/api/routes/middlewares"
@@ -40,6 +41,7 @@ func startServer() error {
 
 	e := echo.New()
 	e.Use(middleware.Logger())
+	e.Use(metricsMiddleware)
 
 	// Populates configuration based on environment variables prefixed with 'API_'
 	var cfg config
@@ -49,7 +51,7 @@ func startServer() error {
 
 	logrus.Info("Connecting to MongoDB")
 
-	clientOptions := options.Client().ApplyURI(cfg.MongoURI)
+	clientOptions := options.Client().ApplyURI(cfg.MongoURI).SetMonitor(mongoMonitor())
 	client, err := mongodriver.Connect(context.TODO(), clientOptions)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to connect to MongoDB")
@@ -78,10 +80,17 @@ func startServer() error {
 		cache = storecache.NewNullCache()
 	}
 
+	// The authentication limits are kept in memory when the store cache is
+	// disabled, which only limits the failures seen by each API instance.
+	limiterCache := cache
+	if !cfg.StoreCache || limiterCache == nil {
+		limiterCache = storecache.NewMemoryCache()
+	}
+
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			store := mongo.NewStore(client.Database("main"), cache)
-			ctx := apicontext.NewContext(store, c)
+			ctx := apicontext.NewContext(store, limiterCache, c)
 
 			return next(ctx)
 		}
@@ -93,6 +102,9 @@ func startServer() error {
 	// Internal routes only accessible by other services in the local container network
 	internalAPI := e.Group("/internal")
 
+	// Administration routes, which are not exposed by the API gateway either
+	adminAPI := e.Group("/admin")
+
 	internalAPI.GET(routes.AuthRequestURL, apicontext.Handler(routes.AuthRequest), apicontext.Middleware(routes.AuthMiddleware))
 	publicAPI.POST(routes.AuthDeviceURL, apicontext.Handler(routes.AuthDevice))
 	publicAPI.POST(routes.AuthDeviceURLV2, apicontext.Handler(routes.AuthDevice))
@@ -102,21 +114,48 @@ func startServer() error {
 	internalAPI.GET(routes.AuthUserTokenURL, apicontext.Handler(routes.AuthGetToken))
 	publicAPI.POST(routes.AuthPublicKeyURL, apicontext.Handler(routes.AuthPublicKey))
 	publicAPI.GET(routes.AuthUserTokenURL, apicontext.Handler(routes.AuthSwapToken))
+	publicAPI.GET(routes.AuthBreakGlassKeysURL, apicontext.Handler(routes.AuthBreakGlassKeys))
 
 	publicAPI.PATCH(routes.UpdateUserDataURL, apicontext.Handler(routes.UpdateUserData))
 	publicAPI.PATCH(routes.UpdateUserPasswordURL, apicontext.Handler(routes.UpdateUserPassword))
+	publicAPI.POST(routes.EnrollMFAURL, apicontext.Handler(routes.EnrollMFA))
+	publicAPI.POST(routes.EnableMFAURL, apicontext.Handler(routes.EnableMFA))
+	publicAPI.POST(routes.DisableMFAURL, apicontext.Handler(routes.DisableMFA))
+	internalAPI.POST(routes.AuthMFAURL, apicontext.Handler(routes.AuthMFA))
+	internalAPI.POST(routes.CheckAuthLimitURL, apicontext.Handler(routes.CheckAuthLimit))
+	internalAPI.POST(routes.ReportAuthFailureURL, apicontext.Handler(routes.ReportAuthFailure))
+	adminAPI.GET(routes.ListAuthBansURL, apicontext.Handler(routes.ListAuthBans))
+	adminAPI.DELETE(routes.DeleteAuthBanURL, apicontext.Handler(routes.DeleteAuthBan))
 	publicAPI.PUT(routes.EditSessionRecordStatusURL, apicontext.Handler(routes.EditSessionRecordStatus))
 	publicAPI.GET(routes.GetSessionRecordURL, apicontext.Handler(routes.GetSessionRecord))
+	publicAPI.PUT(routes.EditPortForwardingStatusURL, apicontext.Handler(routes.EditPortForwardingStatus))
+	publicAPI.GET(routes.GetPortForwardingURL, apicontext.Handler(routes.GetPortForwarding))
+	publicAPI.PUT(routes.EditAgentForwardingStatusURL, apicontext.Handler(routes.EditAgentForwardingStatus))
+	publicAPI.GET(routes.GetAgentForwardingURL, apicontext.Handler(routes.GetAgentForwarding))
+	publicAPI.PUT(routes.EditMFAStatusURL, apicontext.Handler(routes.EditMFAStatus))
+	publicAPI.GET(routes.GetMFAURL, apicontext.Handler(routes.GetMFA))
 
 	publicAPI.GET(routes.GetDeviceListURL,
 		middlewares.Authorize(apicontext.Handler(routes.GetDeviceList)))
 	publicAPI.GET(routes.GetDeviceURL,
 		middlewares.Authorize(apicontext.Handler(routes.GetDevice)))
+	publicAPI.GET(routes.GetDeviceContainersURL,
+		middlewares.Authorize(apicontext.Handler(routes.GetDeviceContainers)))
+	publicAPI.POST(routes.CreateBreakGlassLoginsURL, apicontext.Handler(routes.CreateBreakGlassLogins))
+	publicAPI.GET(routes.GetBreakGlassLoginsURL,
+		middlewares.Authorize(apicontext.Handler(routes.GetBreakGlassLogins)))
 	publicAPI.DELETE(routes.DeleteDeviceURL, apicontext.Handler(routes.DeleteDevice))
 	publicAPI.PATCH(routes.RenameDeviceURL, apicontext.Handler(routes.RenameDevice))
 	internalAPI.POST(routes.OfflineDeviceURL, apicontext.Handler(routes.OfflineDevice))
 	internalAPI.GET(routes.LookupDeviceURL, apicontext.Handler(routes.LookupDevice))
 	publicAPI.PATCH(routes.UpdateStatusURL, apicontext.Handler(routes.UpdatePendingStatus))
+	publicAPI.POST(routes.ExecDeviceURL, apicontext.Handler(routes.ExecDevice))
+	publicAPI.POST(routes.ExecFleetURL, apicontext.Handler(routes.ExecFleet))
+	publicAPI.POST(routes.CollectDiagnosticsURL, apicontext.Handler(routes.CollectDiagnostics))
+	publicAPI.GET(routes.GetJobListURL,
+		middlewares.Authorize(apicontext.Handler(routes.GetJobList)))
+	publicAPI.GET(routes.GetJobURL,
+		middlewares.Authorize(apicontext.Handler(routes.GetJob)))
 	publicAPI.GET(routes.GetSessionsURL,
 		middlewares.Authorize(apicontext.Handler(routes.GetSessionList)))
 	publicAPI.GET(routes.GetSessionURL,
@@ -125,6 +164,8 @@ func startServer() error {
 	internalAPI.POST(routes.CreateSessionURL, apicontext.Handler(routes.CreateSession))
 	internalAPI.POST(routes.FinishSessionURL, apicontext.Handler(routes.FinishSession))
 	internalAPI.POST(routes.RecordSessionURL, apicontext.Handler(routes.RecordSession))
+	internalAPI.POST(routes.CreateSessionForwardURL, apicontext.Handler(routes.CreateSessionForward))
+	internalAPI.PATCH(routes.SetSessionStateURL, apicontext.Handler(routes.SetSessionState))
 	publicAPI.GET(routes.PlaySessionURL, apicontext.Handler(routes.PlaySession))
 	publicAPI.DELETE(routes.RecordSessionURL, apicontext.Handler(routes.DeleteRecordedSession))
 
@@ -136,16 +177,28 @@ func startServer() error {
 	publicAPI.PUT(routes.UpdatePublicKeyURL, apicontext.Handler(routes.UpdatePublicKey))
 	publicAPI.DELETE(routes.DeletePublicKeyURL, apicontext.Handler(routes.DeletePublicKey))
 	internalAPI.GET(routes.GetPublicKeyURL, apicontext.Handler(routes.GetPublicKey))
+	internalAPI.GET(routes.LookupPublicKeyURL, apicontext.Handler(routes.GetPublicKey))
 	internalAPI.POST(routes.CreatePrivateKeyURL, apicontext.Handler(routes.CreatePrivateKey))
 	internalAPI.POST(routes.EvaluateKeyURL, apicontext.Handler(routes.EvaluateKeyHostname))
+	publicAPI.GET(routes.GetCertificateAuthorityURL, apicontext.Handler(routes.GetCertificateAuthority))
+	internalAPI.GET(routes.InternalGetCertificateAuthorityURL, apicontext.Handler(routes.GetCertificateAuthority))
+	internalAPI.GET(routes.LookupCertificateAuthorityURL, apicontext.Handler(routes.LookupCertificateAuthority))
+	publicAPI.POST(routes.CreateCertificateURL, apicontext.Handler(routes.CreateCertificate))
 
 	publicAPI.GET(routes.ListNamespaceURL, apicontext.Handler(routes.GetNamespaceList))
 	publicAPI.GET(routes.GetNamespaceURL, apicontext.Handler(routes.GetNamespace))
+	internalAPI.GET(routes.GetNamespaceURL, apicontext.Handler(routes.GetNamespace))
 	publicAPI.POST(routes.CreateNamespaceURL, apicontext.Handler(routes.CreateNamespace))
 	publicAPI.DELETE(routes.DeleteNamespaceURL, apicontext.Handler(routes.DeleteNamespace))
 	publicAPI.PUT(routes.EditNamespaceURL, apicontext.Handler(routes.EditNamespace))
 	publicAPI.PATCH(routes.AddNamespaceUserURL, apicontext.Handler(routes.AddNamespaceUser))
 	publicAPI.PATCH(routes.RemoveNamespaceUserURL, apicontext.Handler(routes.RemoveNamespaceUser))
+	publicAPI.GET(routes.ExportKnownHostsURL,
+		middlewares.Authorize(apicontext.Handler(routes.ExportKnownHosts)))
+	publicAPI.GET(routes.ExportSSHConfigURL,
+		middlewares.Authorize(apicontext.Handler(routes.ExportSSHConfig)))
+
+	e.GET(MetricsURL, echo.WrapHandler(promhttp.Handler()))
 
 	e.Logger.Fatal(e.Start(":8080"))
 
@@ -2,10 +2,19 @@ package sessionmngr
 
 import (
 	"context"
+	"errors"
 
 	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/clock"
 	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/shellhub-io/shellhub/pkg/validator"
+)
+
+var (
+	ErrInvalidForward         = errors.New("invalid port forwarding")
+	ErrPortForwardingDisabled = errors.New("port forwarding is disabled")
+	ErrInvalidSessionState    = errors.New("invalid session state")
 )
 
 type Service interface {
@@ -14,6 +23,8 @@ type Service interface {
 	CreateSession(ctx context.Context, session models.Session) (*models.Session, error)
 	DeactivateSession(ctx context.Context, uid models.UID) error
 	SetSessionAuthenticated(ctx context.Context, uid models.UID, authenticated bool) error
+	CreateSessionForward(ctx context.Context, uid models.UID, forward *models.SessionForward) error
+	SetSessionState(ctx context.Context, uid models.UID, state string) error
 }
 
 type service struct {
@@ -43,3 +54,38 @@ func (s *service) DeactivateSession(ctx context.Context, uid models.UID) error {
 func (s *service) SetSessionAuthenticated(ctx context.Context, uid models.UID, authenticated bool) error {
 	return s.store.SessionSetAuthenticated(ctx, uid, authenticated)
 }
+
+// SetSessionState marks a persistent session as attached or detached.
+func (s *service) SetSessionState(ctx context.Context, uid models.UID, state string) error {
+	if state != models.SessionStateAttached && state != models.SessionStateDetached {
+		return ErrInvalidSessionState
+	}
+
+	return s.store.SessionSetState(ctx, uid, state)
+}
+
+// CreateSessionForward logs a port forwarding against the session, failing
+// when port forwarding is disabled in the session's namespace.
+func (s *service) CreateSessionForward(ctx context.Context, uid models.UID, forward *models.SessionForward) error {
+	if _, err := validator.ValidateStruct(forward); err != nil {
+		return ErrInvalidForward
+	}
+
+	session, err := s.store.SessionGet(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := s.store.NamespaceGet(ctx, session.TenantID)
+	if err != nil {
+		return err
+	}
+
+	if namespace.Settings == nil || !namespace.Settings.PortForwarding {
+		return ErrPortForwardingDisabled
+	}
+
+	forward.CreatedAt = clock.Now()
+
+	return s.store.SessionCreateForward(ctx, uid, forward)
+}
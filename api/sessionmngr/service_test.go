@@ -285,3 +285,129 @@ func TestSetSessionAuthenticated(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestCreateSessionForward(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	session := &models.Session{UID: "uid", TenantID: "tenant"}
+	enabled := &models.Namespace{TenantID: "tenant", Settings: &models.NamespaceSettings{PortForwarding: true}}
+	disabled := &models.Namespace{TenantID: "tenant", Settings: &models.NamespaceSettings{PortForwarding: false}}
+
+	forward := &models.SessionForward{Type: models.SessionForwardLocal, Host: "localhost", Port: 8080}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name          string
+		uid           models.UID
+		forward       *models.SessionForward
+		requiredMocks func()
+		expected      error
+	}{
+		{
+			name:          "CreateSessionForward fails when the forward type is invalid",
+			uid:           models.UID(session.UID),
+			forward:       &models.SessionForward{Type: "dynamic"},
+			requiredMocks: func() {},
+			expected:      ErrInvalidForward,
+		},
+		{
+			name:    "CreateSessionForward fails when the session is not found",
+			uid:     models.UID(session.UID),
+			forward: forward,
+			requiredMocks: func() {
+				mock.On("SessionGet", ctx, models.UID(session.UID)).Return(nil, Err).Once()
+			},
+			expected: Err,
+		},
+		{
+			name:    "CreateSessionForward fails when port forwarding is disabled",
+			uid:     models.UID(session.UID),
+			forward: forward,
+			requiredMocks: func() {
+				mock.On("SessionGet", ctx, models.UID(session.UID)).Return(session, nil).Once()
+				mock.On("NamespaceGet", ctx, session.TenantID).Return(disabled, nil).Once()
+			},
+			expected: ErrPortForwardingDisabled,
+		},
+		{
+			name:    "CreateSessionForward succeeds",
+			uid:     models.UID(session.UID),
+			forward: forward,
+			requiredMocks: func() {
+				mock.On("SessionGet", ctx, models.UID(session.UID)).Return(session, nil).Once()
+				mock.On("NamespaceGet", ctx, session.TenantID).Return(enabled, nil).Once()
+				mock.On("SessionCreateForward", ctx, models.UID(session.UID), forward).Return(nil).Once()
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.CreateSessionForward(ctx, tc.uid, tc.forward)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestSetSessionState(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name          string
+		uid           models.UID
+		state         string
+		requiredMocks func()
+		expected      error
+	}{
+		{
+			name:          "SetSessionState fails when the state is invalid",
+			uid:           models.UID("uid"),
+			state:         "closed",
+			requiredMocks: func() {},
+			expected:      ErrInvalidSessionState,
+		},
+		{
+			name:  "SetSessionState fails",
+			uid:   models.UID("_uid"),
+			state: models.SessionStateDetached,
+			requiredMocks: func() {
+				mock.On("SessionSetState", ctx, models.UID("_uid"), models.SessionStateDetached).
+					Return(Err).Once()
+			},
+			expected: Err,
+		},
+		{
+			name:  "SetSessionState succeeds",
+			uid:   models.UID("uid"),
+			state: models.SessionStateAttached,
+			requiredMocks: func() {
+				mock.On("SessionSetState", ctx, models.UID("uid"), models.SessionStateAttached).
+					Return(nil).Once()
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			err := s.SetSessionState(ctx, tc.uid, tc.state)
+			assert.Equal(t, tc.expected, err)
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
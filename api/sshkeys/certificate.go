@@ -0,0 +1,239 @@
+package sshkeys
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrUnauthorized              = errors.New("unauthorized")
+	ErrInvalidCertificateRequest = errors.New("invalid certificate request")
+)
+
+const (
+	// DefaultCertificateValidity is the lifetime of the certificates whose
+	// request does not set it.
+	DefaultCertificateValidity = time.Hour
+	// MaxCertificateValidity is the longest lifetime of the certificates.
+	MaxCertificateValidity = 24 * time.Hour
+
+	// certificateClockSkew backdates the certificates to tolerate the clock
+	// drifts of the gateway.
+	certificateClockSkew = 5 * time.Minute
+)
+
+// certificateExtensions are the extensions of the user certificates known by
+// the gateway, which are the ones of OpenSSH.
+var certificateExtensions = map[string]bool{
+	"permit-X11-forwarding":   true,
+	"permit-agent-forwarding": true,
+	"permit-port-forwarding":  true,
+	"permit-pty":              true,
+	"permit-user-rc":          true,
+}
+
+// defaultCertificateExtensions are the extensions of the certificates whose
+// request does not set them, which only allow interactive sessions.
+var defaultCertificateExtensions = []string{"permit-pty"}
+
+// GetCertificateAuthority returns the certificate authority of the namespace,
+// which is created on its first use.
+func (s *service) GetCertificateAuthority(ctx context.Context, tenant string) (*models.CertificateAuthority, error) {
+	ca, err := s.store.CertificateAuthorityGet(ctx, tenant)
+	if err != store.ErrNoDocuments {
+		return ca, err
+	}
+
+	if _, err := s.store.NamespaceGet(ctx, tenant); err != nil {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	ca = &models.CertificateAuthority{
+		TenantID:    tenant,
+		PublicKey:   ssh.MarshalAuthorizedKey(sshPub),
+		Fingerprint: ssh.FingerprintSHA256(sshPub),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		CreatedAt:   clock.Now(),
+	}
+
+	if err := s.store.CertificateAuthorityCreate(ctx, ca); err != nil {
+		// The authority was created by a concurrent request.
+		if err == store.ErrDuplicate {
+			return s.store.CertificateAuthorityGet(ctx, tenant)
+		}
+
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+// GetCertificateAuthorityByFingerprint returns the certificate authority whose
+// public key has the fingerprint, which tells the namespace that signed a
+// certificate.
+func (s *service) GetCertificateAuthorityByFingerprint(ctx context.Context, fingerprint string) (*models.CertificateAuthority, error) {
+	return s.store.CertificateAuthorityGetByFingerprint(ctx, fingerprint)
+}
+
+// CreateCertificate issues a user certificate signed by the certificate
+// authority of the namespace to one of its members.
+func (s *service) CreateCertificate(ctx context.Context, tenant, userID string, req *models.CertificateRequest) (*models.Certificate, error) {
+	user, _, err := s.store.UserGetByID(ctx, userID, false)
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return nil, ErrUnauthorized
+		}
+
+		return nil, err
+	}
+
+	ns, err := s.store.NamespaceGet(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	member := false
+	for _, id := range ns.Members {
+		if id == user.ID {
+			member = true
+
+			break
+		}
+	}
+
+	if !member {
+		return nil, ErrUnauthorized
+	}
+
+	pubKey, _, _, _, err := ssh.ParseAuthorizedKey(req.PublicKey) //nolint:dogsled
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+
+	// A certificate can not be certified itself.
+	if _, ok := pubKey.(*ssh.Certificate); ok {
+		return nil, ErrInvalidFormat
+	}
+
+	validity := time.Duration(req.Validity) * time.Second
+	if req.Validity == 0 {
+		validity = DefaultCertificateValidity
+	}
+
+	if validity < 0 || validity > MaxCertificateValidity || len(req.Principals) == 0 {
+		return nil, ErrInvalidCertificateRequest
+	}
+
+	for _, principal := range req.Principals {
+		if principal == "" {
+			return nil, ErrInvalidCertificateRequest
+		}
+	}
+
+	extensions := req.Extensions
+	if extensions == nil {
+		extensions = defaultCertificateExtensions
+	}
+
+	permissions := make(map[string]string, len(extensions))
+	for _, extension := range extensions {
+		if !certificateExtensions[extension] {
+			return nil, ErrInvalidCertificateRequest
+		}
+
+		permissions[extension] = ""
+	}
+
+	ca, err := s.GetCertificateAuthority(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := parseCertificateAuthority(ca)
+	if err != nil {
+		return nil, err
+	}
+
+	serial := make([]byte, 8)
+	if _, err := rand.Read(serial); err != nil {
+		return nil, err
+	}
+
+	now := clock.Now()
+
+	cert := &ssh.Certificate{
+		Key:             pubKey,
+		Serial:          binary.BigEndian.Uint64(serial),
+		CertType:        ssh.UserCert,
+		KeyId:           user.Username,
+		ValidPrincipals: req.Principals,
+		ValidAfter:      uint64(now.Add(-certificateClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(validity).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: permissions,
+		},
+	}
+
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(permissions))
+	for extension := range permissions {
+		names = append(names, extension)
+	}
+
+	sort.Strings(names)
+
+	return &models.Certificate{
+		Data:        ssh.MarshalAuthorizedKey(cert),
+		Serial:      cert.Serial,
+		KeyID:       cert.KeyId,
+		Principals:  cert.ValidPrincipals,
+		Extensions:  names,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0),
+	}, nil
+}
+
+// parseCertificateAuthority returns the signer of the certificate authority.
+func parseCertificateAuthority(ca *models.CertificateAuthority) (ssh.Signer, error) {
+	block, _ := pem.Decode(ca.PrivateKey)
+	if block == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
@@ -0,0 +1,233 @@
+package sshkeys
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/api/store/mocks"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	mocklib "github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGetCertificateAuthority(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	namespace := &models.Namespace{Name: "namespace", Owner: "owner", TenantID: "tenant"}
+	ca := &models.CertificateAuthority{TenantID: namespace.TenantID, PublicKey: []byte("public"), PrivateKey: []byte("private")}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		expected      error
+	}{
+		{
+			name: "GetCertificateAuthority returns the existing authority",
+			requiredMocks: func() {
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(ca, nil).Once()
+			},
+			expected: nil,
+		},
+		{
+			name: "GetCertificateAuthority fails when the namespace is not found",
+			requiredMocks: func() {
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+			},
+			expected: store.ErrNoDocuments,
+		},
+		{
+			name: "GetCertificateAuthority fails when the authority can not be created",
+			requiredMocks: func() {
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("CertificateAuthorityCreate", ctx, mocklib.Anything).Return(Err).Once()
+			},
+			expected: Err,
+		},
+		{
+			name: "GetCertificateAuthority returns the authority created concurrently",
+			requiredMocks: func() {
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("CertificateAuthorityCreate", ctx, mocklib.Anything).Return(store.ErrDuplicate).Once()
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(ca, nil).Once()
+			},
+			expected: nil,
+		},
+		{
+			name: "GetCertificateAuthority creates the authority",
+			requiredMocks: func() {
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("CertificateAuthorityCreate", ctx, mocklib.Anything).Return(nil).Once()
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			returned, err := s.GetCertificateAuthority(ctx, namespace.TenantID)
+			assert.Equal(t, tc.expected, err)
+			if err == nil {
+				assert.Equal(t, namespace.TenantID, returned.TenantID)
+			}
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestGetCertificateAuthorityByFingerprint(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	ca := &models.CertificateAuthority{TenantID: "tenant", PublicKey: []byte("public"), Fingerprint: "fingerprint"}
+
+	mock.On("CertificateAuthorityGetByFingerprint", ctx, ca.Fingerprint).Return(ca, nil).Once()
+	mock.On("CertificateAuthorityGetByFingerprint", ctx, "other").Return(nil, store.ErrNoDocuments).Once()
+
+	returned, err := s.GetCertificateAuthorityByFingerprint(ctx, ca.Fingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, ca, returned)
+
+	_, err = s.GetCertificateAuthorityByFingerprint(ctx, "other")
+	assert.Equal(t, store.ErrNoDocuments, err)
+
+	mock.AssertExpectations(t)
+}
+
+func TestCreateCertificate(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	// The authority is created by the first successful request and
+	// returned by the following ones.
+	var ca *models.CertificateAuthority
+
+	user := &models.User{Name: "user", Username: "username", ID: "id"}
+	namespace := &models.Namespace{Name: "namespace", Owner: "owner", TenantID: "tenant", Members: []interface{}{"owner", user.ID}}
+	other := &models.Namespace{Name: "other", Owner: "owner", TenantID: "tenant", Members: []interface{}{"owner"}}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err)
+
+	key := ssh.MarshalAuthorizedKey(sshPub)
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		req           *models.CertificateRequest
+		expected      error
+	}{
+		{
+			name: "CreateCertificate fails when the user is not a member of the namespace",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(other, nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: key, Principals: []string{"root"}},
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "CreateCertificate fails when the public key is invalid",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: []byte("invalid"), Principals: []string{"root"}},
+			expected: ErrInvalidFormat,
+		},
+		{
+			name: "CreateCertificate fails when the request has no principals",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: key},
+			expected: ErrInvalidCertificateRequest,
+		},
+		{
+			name: "CreateCertificate fails when the validity is too long",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: key, Principals: []string{"root"}, Validity: int(MaxCertificateValidity.Seconds()) + 1},
+			expected: ErrInvalidCertificateRequest,
+		},
+		{
+			name: "CreateCertificate fails when an extension is unknown",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: key, Principals: []string{"root"}, Extensions: []string{"permit-everything"}},
+			expected: ErrInvalidCertificateRequest,
+		},
+		{
+			name: "CreateCertificate succeeds",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Twice()
+				mock.On("CertificateAuthorityGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+				mock.On("CertificateAuthorityCreate", ctx, mocklib.Anything).Run(func(args mocklib.Arguments) {
+					ca = args.Get(1).(*models.CertificateAuthority)
+				}).Return(nil).Once()
+			},
+			req:      &models.CertificateRequest{PublicKey: key, Principals: []string{"root"}, Extensions: []string{"permit-pty", "permit-port-forwarding"}},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+
+			cert, err := s.CreateCertificate(ctx, namespace.TenantID, user.ID, tc.req)
+			assert.Equal(t, tc.expected, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, user.Username, cert.KeyID)
+			assert.Equal(t, []string{"permit-port-forwarding", "permit-pty"}, cert.Extensions)
+
+			parsed, _, _, _, err := ssh.ParseAuthorizedKey(cert.Data) //nolint:dogsled
+			assert.NoError(t, err)
+
+			caKey, _, _, _, err := ssh.ParseAuthorizedKey(ca.PublicKey) //nolint:dogsled
+			assert.NoError(t, err)
+
+			checker := &ssh.CertChecker{
+				IsUserAuthority: func(auth ssh.PublicKey) bool {
+					return string(auth.Marshal()) == string(caKey.Marshal())
+				},
+			}
+
+			assert.NoError(t, checker.CheckCert("root", parsed.(*ssh.Certificate)))
+			assert.Error(t, checker.CheckCert("admin", parsed.(*ssh.Certificate)))
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
@@ -30,6 +30,9 @@ type Service interface {
 	UpdatePublicKey(ctx context.Context, fingerprint, tenant string, key *models.PublicKeyUpdate) (*models.PublicKey, error)
 	DeletePublicKey(ctx context.Context, fingerprint, tenant string) error
 	CreatePrivateKey(ctx context.Context) (*models.PrivateKey, error)
+	GetCertificateAuthority(ctx context.Context, tenant string) (*models.CertificateAuthority, error)
+	GetCertificateAuthorityByFingerprint(ctx context.Context, fingerprint string) (*models.CertificateAuthority, error)
+	CreateCertificate(ctx context.Context, tenant, userID string, req *models.CertificateRequest) (*models.Certificate, error)
 }
 
 type service struct {
@@ -69,7 +72,7 @@ func (s *service) CreatePublicKey(ctx context.Context, key *models.PublicKey) er
 		return ErrInvalidFormat
 	}
 
-	key.Fingerprint = ssh.FingerprintLegacyMD5(pubKey)
+	key.Fingerprint = ssh.FingerprintSHA256(pubKey)
 
 	returnedKey, err := s.store.PublicKeyGet(ctx, key.Fingerprint, apicontext.TenantFromContext(ctx).ID)
 	if err != nil && err != store.ErrNoDocuments {
@@ -116,8 +119,9 @@ func (s *service) CreatePrivateKey(ctx context.Context) (*models.PrivateKey, err
 			Type:  "RSA PRIVATE KEY",
 			Bytes: x509.MarshalPKCS1PrivateKey(key),
 		}),
-		Fingerprint: ssh.FingerprintLegacyMD5(pubKey),
-		CreatedAt:   clock.Now(),
+		Fingerprint:       ssh.FingerprintSHA256(pubKey),
+		LegacyFingerprint: ssh.FingerprintLegacyMD5(pubKey),
+		CreatedAt:         clock.Now(),
 	}
 
 	if err := s.store.PrivateKeyCreate(ctx, privateKey); err != nil {
@@ -0,0 +1,13 @@
+package store
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+type BreakGlassStore interface {
+	BreakGlassLoginCreate(ctx context.Context, logins []models.BreakGlassLogin) error
+	BreakGlassLoginList(ctx context.Context, uid models.UID, pagination paginator.Query) ([]models.BreakGlassLogin, int, error)
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/shellhub-io/shellhub/pkg/clock"
+)
+
+type memoryItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+var _ Cache = &memoryCache{}
+
+// NewMemoryCache creates and returns a new in-memory cache, which is only
+// shared within the process.
+func NewMemoryCache() Cache {
+	return &memoryCache{items: make(map[string]memoryItem)}
+}
+
+// Get gets the cache value for the given key.
+// NOTE: missing key is not an error.
+func (c *memoryCache) Get(ctx context.Context, key string, value interface{}) error {
+	c.mu.Lock()
+	item, ok := c.items[key]
+	c.mu.Unlock()
+
+	if !ok || item.expired(clock.Now()) {
+		cacheLookups.WithLabelValues("miss").Inc()
+
+		return nil
+	}
+
+	cacheLookups.WithLabelValues("hit").Inc()
+
+	if value == nil {
+		return nil
+	}
+
+	return json.Unmarshal(item.data, value)
+}
+
+// Set puts value into cache with key and expire time, which never expires when
+// the ttl is zero.
+func (c *memoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	now := clock.Now()
+
+	item := memoryItem{data: data}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The expired items are only evicted by the writes.
+	for k, v := range c.items {
+		if v.expired(now) {
+			delete(c.items, k)
+		}
+	}
+
+	c.items[key] = item
+
+	return nil
+}
+
+// Delete deletes cached value by given key.
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+
+	return nil
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
@@ -33,9 +33,15 @@ func NewRedisCache(uri string) (Cache, error) {
 func (c *redisCache) Get(ctx context.Context, key string, value interface{}) error {
 	err := c.cache.Get(ctx, key, value)
 	if err == rediscache.ErrCacheMiss {
+		cacheLookups.WithLabelValues("miss").Inc()
+
 		return nil
 	}
 
+	if err == nil {
+		cacheLookups.WithLabelValues("hit").Inc()
+	}
+
 	return err
 }
 
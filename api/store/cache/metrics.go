@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// cacheLookups counts the lookups of the cache by result (hit or miss), from
+// where the hit ratio is taken.
+var cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "shellhub",
+	Subsystem: "api",
+	Name:      "store_cache_lookups_total",
+	Help:      "Number of store cache lookups by result.",
+}, []string{"result"})
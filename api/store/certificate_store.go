@@ -0,0 +1,13 @@
+package store
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+type CertificateStore interface {
+	CertificateAuthorityGet(ctx context.Context, tenant string) (*models.CertificateAuthority, error)
+	CertificateAuthorityGetByFingerprint(ctx context.Context, fingerprint string) (*models.CertificateAuthority, error)
+	CertificateAuthorityCreate(ctx context.Context, ca *models.CertificateAuthority) error
+}
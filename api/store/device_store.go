@@ -17,6 +17,7 @@ type DeviceStore interface {
 	DeviceSetOnline(ctx context.Context, uid models.UID, online bool) error
 	DeviceUpdateStatus(ctx context.Context, uid models.UID, status string) error
 	DeviceGetByMac(ctx context.Context, mac, tenant, status string) (*models.Device, error)
+	DeviceGetByIdentity(ctx context.Context, source, id, tenant, status string) (*models.Device, error)
 	DeviceGetByName(ctx context.Context, name, tenant string) (*models.Device, error)
 	DeviceGetByUID(ctx context.Context, uid models.UID, tenant string) (*models.Device, error)
 }
@@ -0,0 +1,15 @@
+package store
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+)
+
+type JobStore interface {
+	JobList(ctx context.Context, pagination paginator.Query) ([]models.Job, int, error)
+	JobGet(ctx context.Context, uid models.UID) (*models.Job, error)
+	JobCreate(ctx context.Context, job *models.Job) error
+	JobUpdateResult(ctx context.Context, uid models.UID, result *models.JobResult, entry *models.JobAuditEntry) error
+}
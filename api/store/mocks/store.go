@@ -16,6 +16,110 @@ type Store struct {
 	mock.Mock
 }
 
+// BreakGlassLoginCreate provides a mock function with given fields: ctx, logins
+func (_m *Store) BreakGlassLoginCreate(ctx context.Context, logins []models.BreakGlassLogin) error {
+	ret := _m.Called(ctx, logins)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.BreakGlassLogin) error); ok {
+		r0 = rf(ctx, logins)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BreakGlassLoginList provides a mock function with given fields: ctx, uid, pagination
+func (_m *Store) BreakGlassLoginList(ctx context.Context, uid models.UID, pagination paginator.Query) ([]models.BreakGlassLogin, int, error) {
+	ret := _m.Called(ctx, uid, pagination)
+
+	var r0 []models.BreakGlassLogin
+	if rf, ok := ret.Get(0).(func(context.Context, models.UID, paginator.Query) []models.BreakGlassLogin); ok {
+		r0 = rf(ctx, uid, pagination)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.BreakGlassLogin)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(context.Context, models.UID, paginator.Query) int); ok {
+		r1 = rf(ctx, uid, pagination)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, models.UID, paginator.Query) error); ok {
+		r2 = rf(ctx, uid, pagination)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// CertificateAuthorityCreate provides a mock function with given fields: ctx, ca
+func (_m *Store) CertificateAuthorityCreate(ctx context.Context, ca *models.CertificateAuthority) error {
+	ret := _m.Called(ctx, ca)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.CertificateAuthority) error); ok {
+		r0 = rf(ctx, ca)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CertificateAuthorityGet provides a mock function with given fields: ctx, tenant
+func (_m *Store) CertificateAuthorityGet(ctx context.Context, tenant string) (*models.CertificateAuthority, error) {
+	ret := _m.Called(ctx, tenant)
+
+	var r0 *models.CertificateAuthority
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.CertificateAuthority); ok {
+		r0 = rf(ctx, tenant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CertificateAuthority)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CertificateAuthorityGetByFingerprint provides a mock function with given fields: ctx, fingerprint
+func (_m *Store) CertificateAuthorityGetByFingerprint(ctx context.Context, fingerprint string) (*models.CertificateAuthority, error) {
+	ret := _m.Called(ctx, fingerprint)
+
+	var r0 *models.CertificateAuthority
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.CertificateAuthority); ok {
+		r0 = rf(ctx, fingerprint)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CertificateAuthority)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fingerprint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeviceCreate provides a mock function with given fields: ctx, d, hostname
 func (_m *Store) DeviceCreate(ctx context.Context, d models.Device, hostname string) error {
 	ret := _m.Called(ctx, d, hostname)
@@ -67,6 +171,29 @@ func (_m *Store) DeviceGet(ctx context.Context, uid models.UID) (*models.Device,
 	return r0, r1
 }
 
+// DeviceGetByIdentity provides a mock function with given fields: ctx, source, id, tenant, status
+func (_m *Store) DeviceGetByIdentity(ctx context.Context, source string, id string, tenant string, status string) (*models.Device, error) {
+	ret := _m.Called(ctx, source, id, tenant, status)
+
+	var r0 *models.Device
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) *models.Device); ok {
+		r0 = rf(ctx, source, id, tenant, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Device)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, source, id, tenant, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeviceGetByMac provides a mock function with given fields: ctx, mac, tenant, status
 func (_m *Store) DeviceGetByMac(ctx context.Context, mac string, tenant string, status string) (*models.Device, error) {
 	ret := _m.Called(ctx, mac, tenant, status)
@@ -358,6 +485,87 @@ func (_m *Store) GetStats(ctx context.Context) (*models.Stats, error) {
 	return r0, r1
 }
 
+// JobCreate provides a mock function with given fields: ctx, job
+func (_m *Store) JobCreate(ctx context.Context, job *models.Job) error {
+	ret := _m.Called(ctx, job)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Job) error); ok {
+		r0 = rf(ctx, job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// JobGet provides a mock function with given fields: ctx, uid
+func (_m *Store) JobGet(ctx context.Context, uid models.UID) (*models.Job, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 *models.Job
+	if rf, ok := ret.Get(0).(func(context.Context, models.UID) *models.Job); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Job)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.UID) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// JobList provides a mock function with given fields: ctx, pagination
+func (_m *Store) JobList(ctx context.Context, pagination paginator.Query) ([]models.Job, int, error) {
+	ret := _m.Called(ctx, pagination)
+
+	var r0 []models.Job
+	if rf, ok := ret.Get(0).(func(context.Context, paginator.Query) []models.Job); ok {
+		r0 = rf(ctx, pagination)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Job)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(context.Context, paginator.Query) int); ok {
+		r1 = rf(ctx, pagination)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, paginator.Query) error); ok {
+		r2 = rf(ctx, pagination)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// JobUpdateResult provides a mock function with given fields: ctx, uid, result, entry
+func (_m *Store) JobUpdateResult(ctx context.Context, uid models.UID, result *models.JobResult, entry *models.JobAuditEntry) error {
+	ret := _m.Called(ctx, uid, result, entry)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UID, *models.JobResult, *models.JobAuditEntry) error); ok {
+		r0 = rf(ctx, uid, result, entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LicenseLoad provides a mock function with given fields: ctx
 func (_m *Store) LicenseLoad(ctx context.Context) (*models.License, error) {
 	ret := _m.Called(ctx)
@@ -478,6 +686,27 @@ func (_m *Store) NamespaceGet(ctx context.Context, tenantID string) (*models.Nam
 	return r0, r1
 }
 
+// NamespaceGetAgentForwarding provides a mock function with given fields: ctx, tenantID
+func (_m *Store) NamespaceGetAgentForwarding(ctx context.Context, tenantID string) (bool, error) {
+	ret := _m.Called(ctx, tenantID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, tenantID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NamespaceGetByName provides a mock function with given fields: ctx, tenantID
 func (_m *Store) NamespaceGetByName(ctx context.Context, tenantID string) (*models.Namespace, error) {
 	ret := _m.Called(ctx, tenantID)
@@ -524,6 +753,48 @@ func (_m *Store) NamespaceGetFirst(ctx context.Context, ID string) (*models.Name
 	return r0, r1
 }
 
+// NamespaceGetMFA provides a mock function with given fields: ctx, tenantID
+func (_m *Store) NamespaceGetMFA(ctx context.Context, tenantID string) (bool, error) {
+	ret := _m.Called(ctx, tenantID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, tenantID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NamespaceGetPortForwarding provides a mock function with given fields: ctx, tenantID
+func (_m *Store) NamespaceGetPortForwarding(ctx context.Context, tenantID string) (bool, error) {
+	ret := _m.Called(ctx, tenantID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, tenantID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NamespaceGetSessionRecord provides a mock function with given fields: ctx, tenantID
 func (_m *Store) NamespaceGetSessionRecord(ctx context.Context, tenantID string) (bool, error) {
 	ret := _m.Called(ctx, tenantID)
@@ -621,6 +892,48 @@ func (_m *Store) NamespaceRename(ctx context.Context, tenantID string, name stri
 	return r0, r1
 }
 
+// NamespaceSetAgentForwarding provides a mock function with given fields: ctx, agentForwarding, tenantID
+func (_m *Store) NamespaceSetAgentForwarding(ctx context.Context, agentForwarding bool, tenantID string) error {
+	ret := _m.Called(ctx, agentForwarding, tenantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool, string) error); ok {
+		r0 = rf(ctx, agentForwarding, tenantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NamespaceSetMFA provides a mock function with given fields: ctx, mfa, tenantID
+func (_m *Store) NamespaceSetMFA(ctx context.Context, mfa bool, tenantID string) error {
+	ret := _m.Called(ctx, mfa, tenantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool, string) error); ok {
+		r0 = rf(ctx, mfa, tenantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NamespaceSetPortForwarding provides a mock function with given fields: ctx, portForwarding, tenantID
+func (_m *Store) NamespaceSetPortForwarding(ctx context.Context, portForwarding bool, tenantID string) error {
+	ret := _m.Called(ctx, portForwarding, tenantID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool, string) error); ok {
+		r0 = rf(ctx, portForwarding, tenantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NamespaceSetSessionRecord provides a mock function with given fields: ctx, sessionRecord, tenantID
 func (_m *Store) NamespaceSetSessionRecord(ctx context.Context, sessionRecord bool, tenantID string) error {
 	ret := _m.Called(ctx, sessionRecord, tenantID)
@@ -813,6 +1126,20 @@ func (_m *Store) SessionCreate(ctx context.Context, session models.Session) (*mo
 	return r0, r1
 }
 
+// SessionCreateForward provides a mock function with given fields: ctx, uid, forward
+func (_m *Store) SessionCreateForward(ctx context.Context, uid models.UID, forward *models.SessionForward) error {
+	ret := _m.Called(ctx, uid, forward)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UID, *models.SessionForward) error); ok {
+		r0 = rf(ctx, uid, forward)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SessionCreateRecordFrame provides a mock function with given fields: ctx, uid, recordSession
 func (_m *Store) SessionCreateRecordFrame(ctx context.Context, uid models.UID, recordSession *models.RecordedSession) error {
 	ret := _m.Called(ctx, uid, recordSession)
@@ -980,6 +1307,20 @@ func (_m *Store) SessionSetRecorded(ctx context.Context, uid models.UID, recorde
 	return r0
 }
 
+// SessionSetState provides a mock function with given fields: ctx, uid, state
+func (_m *Store) SessionSetState(ctx context.Context, uid models.UID, state string) error {
+	ret := _m.Called(ctx, uid, state)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.UID, string) error); ok {
+		r0 = rf(ctx, uid, state)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SessionUpdateDeviceUID provides a mock function with given fields: ctx, oldUID, newUID
 func (_m *Store) SessionUpdateDeviceUID(ctx context.Context, oldUID models.UID, newUID models.UID) error {
 	ret := _m.Called(ctx, oldUID, newUID)
@@ -1202,6 +1543,34 @@ func (_m *Store) UserList(ctx context.Context, pagination paginator.Query, filte
 	return r0, r1, r2
 }
 
+// UserSetMFA provides a mock function with given fields: ctx, ID, secret, enabled
+func (_m *Store) UserSetMFA(ctx context.Context, ID string, secret string, enabled bool) error {
+	ret := _m.Called(ctx, ID, secret, enabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, ID, secret, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserSetMFAStep provides a mock function with given fields: ctx, ID, step
+func (_m *Store) UserSetMFAStep(ctx context.Context, ID string, step int64) error {
+	ret := _m.Called(ctx, ID, step)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, ID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UserUpdateAccountStatus provides a mock function with given fields: ctx, ID
 func (_m *Store) UserUpdateAccountStatus(ctx context.Context, ID string) error {
 	ret := _m.Called(ctx, ID)
@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *Store) BreakGlassLoginCreate(ctx context.Context, logins []models.BreakGlassLogin) error {
+	docs := make([]interface{}, 0, len(logins))
+	for _, login := range logins {
+		docs = append(docs, login)
+	}
+
+	_, err := s.db.Collection("break_glass_logins").InsertMany(ctx, docs)
+
+	return fromMongoError(err)
+}
+
+func (s *Store) BreakGlassLoginList(ctx context.Context, uid models.UID, pagination paginator.Query) ([]models.BreakGlassLogin, int, error) {
+	query := []bson.M{
+		{
+			"$match": bson.M{
+				"device_uid": uid,
+			},
+		},
+		{
+			"$sort": bson.M{
+				"logged_at": -1,
+			},
+		},
+	}
+
+	queryCount := append(query, bson.M{"$count": "count"})
+	count, err := aggregateCount(ctx, s.db.Collection("break_glass_logins"), queryCount)
+	if err != nil {
+		return nil, 0, fromMongoError(err)
+	}
+
+	query = append(query, buildPaginationQuery(pagination)...)
+
+	logins := make([]models.BreakGlassLogin, 0)
+	cursor, err := s.db.Collection("break_glass_logins").Aggregate(ctx, query)
+	if err != nil {
+		return logins, count, fromMongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		login := new(models.BreakGlassLogin)
+		if err := cursor.Decode(&login); err != nil {
+			return logins, count, err
+		}
+
+		logins = append(logins, *login)
+	}
+
+	return logins, count, cursor.Err()
+}
@@ -198,10 +198,24 @@ func (s *Store) DeviceDelete(ctx context.Context, uid models.UID) error {
 	return fromMongoError(err)
 }
 
+// defaultDeviceName returns the name given to a device which does not have a
+// preferred hostname. It is derived from its MAC address or, when the device
+// does not have one, from its UID.
+func defaultDeviceName(d models.Device) string {
+	if d.Identity != nil && d.Identity.MAC != "" {
+		return strings.ReplaceAll(d.Identity.MAC, ":", "-")
+	}
+
+	if len(d.UID) > 12 {
+		return d.UID[:12]
+	}
+
+	return d.UID
+}
+
 func (s *Store) DeviceCreate(ctx context.Context, d models.Device, hostname string) error {
-	mac := strings.ReplaceAll(d.Identity.MAC, ":", "-")
 	if hostname == "" {
-		hostname = mac
+		hostname = defaultDeviceName(d)
 	}
 
 	var dev *models.Device
@@ -306,11 +320,11 @@ func (s *Store) DeviceUpdateStatus(ctx context.Context, uid models.UID, status s
 func (s *Store) DeviceGetByMac(ctx context.Context, mac, tenant, status string) (*models.Device, error) {
 	device := new(models.Device)
 	if status != "" {
-		if err := s.db.Collection("devices").FindOne(ctx, bson.M{"tenant_id": tenant, "identity": bson.M{"mac": mac}, "status": status}).Decode(&device); err != nil {
+		if err := s.db.Collection("devices").FindOne(ctx, bson.M{"tenant_id": tenant, "identity.mac": mac, "status": status}).Decode(&device); err != nil {
 			return nil, fromMongoError(err)
 		}
 	} else {
-		if err := s.db.Collection("devices").FindOne(ctx, bson.M{"tenant_id": tenant, "identity": bson.M{"mac": mac}}).Decode(&device); err != nil {
+		if err := s.db.Collection("devices").FindOne(ctx, bson.M{"tenant_id": tenant, "identity.mac": mac}).Decode(&device); err != nil {
 			return nil, fromMongoError(err)
 		}
 	}
@@ -318,6 +332,20 @@ func (s *Store) DeviceGetByMac(ctx context.Context, mac, tenant, status string)
 	return device, nil
 }
 
+func (s *Store) DeviceGetByIdentity(ctx context.Context, source, id, tenant, status string) (*models.Device, error) {
+	query := bson.M{"tenant_id": tenant, "identity.source": source, "identity.id": id}
+	if status != "" {
+		query["status"] = status
+	}
+
+	device := new(models.Device)
+	if err := s.db.Collection("devices").FindOne(ctx, query).Decode(&device); err != nil {
+		return nil, fromMongoError(err)
+	}
+
+	return device, nil
+}
+
 func (s *Store) DeviceGetByName(ctx context.Context, name, tenant string) (*models.Device, error) {
 	device := new(models.Device)
 	if err := s.db.Collection("devices").FindOne(ctx, bson.M{"tenant_id": tenant, "name": name}).Decode(&device); err != nil {
@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/api/paginator"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *Store) JobList(ctx context.Context, pagination paginator.Query) ([]models.Job, int, error) {
+	query := []bson.M{
+		{
+			"$sort": bson.M{
+				"created_at": -1,
+			},
+		},
+	}
+
+	// Only match for the respective tenant if requested
+	if tenant := apicontext.TenantFromContext(ctx); tenant != nil {
+		query = append(query, bson.M{
+			"$match": bson.M{
+				"tenant_id": tenant.ID,
+			},
+		})
+	}
+
+	queryCount := append(query, bson.M{"$count": "count"})
+	count, err := aggregateCount(ctx, s.db.Collection("jobs"), queryCount)
+	if err != nil {
+		return nil, 0, fromMongoError(err)
+	}
+
+	query = append(query, buildPaginationQuery(pagination)...)
+
+	jobs := make([]models.Job, 0)
+	cursor, err := s.db.Collection("jobs").Aggregate(ctx, query)
+	if err != nil {
+		return jobs, count, fromMongoError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		job := new(models.Job)
+		if err := cursor.Decode(&job); err != nil {
+			return jobs, count, err
+		}
+
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, count, cursor.Err()
+}
+
+func (s *Store) JobGet(ctx context.Context, uid models.UID) (*models.Job, error) {
+	query := bson.M{"uid": uid}
+
+	// Only match for the respective tenant if requested
+	if tenant := apicontext.TenantFromContext(ctx); tenant != nil {
+		query["tenant_id"] = tenant.ID
+	}
+
+	job := new(models.Job)
+	if err := s.db.Collection("jobs").FindOne(ctx, query).Decode(&job); err != nil {
+		return nil, fromMongoError(err)
+	}
+
+	return job, nil
+}
+
+func (s *Store) JobCreate(ctx context.Context, job *models.Job) error {
+	_, err := s.db.Collection("jobs").InsertOne(ctx, job)
+
+	return fromMongoError(err)
+}
+
+// JobUpdateResult replaces the result of the job in the device and appends
+// the entry to its audit trail.
+func (s *Store) JobUpdateResult(ctx context.Context, uid models.UID, result *models.JobResult, entry *models.JobAuditEntry) error {
+	update := bson.M{
+		"$set":  bson.M{"results.$": result},
+		"$push": bson.M{"audit": entry},
+	}
+
+	res, err := s.db.Collection("jobs").UpdateOne(ctx, bson.M{"uid": uid, "results.device": result.Device}, update)
+	if err != nil {
+		return fromMongoError(err)
+	}
+
+	if res.MatchedCount < 1 {
+		return store.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -36,6 +36,12 @@ func GenerateMigrations() []migrate.Migration {
 		migration24,
 		migration25,
 		migration26,
+		migration27,
+		migration28,
+		migration29,
+		migration30,
+		migration31,
+		migration32,
 	}
 }
 
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var migration27 = migrate.Migration{
+	Version:     27,
+	Description: "Enable agent forwarding in the namespaces settings",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 27 - Up")
+		if _, err := db.Collection("namespaces").UpdateMany(context.TODO(), bson.M{}, bson.M{"$set": bson.M{"settings.agent_forwarding": true}}); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 27 - Down")
+		if _, err := db.Collection("namespaces").UpdateMany(context.TODO(), bson.M{}, bson.M{"$unset": bson.M{"settings.agent_forwarding": ""}}); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shellhub-io/shellhub/api/pkg/dbtest"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigration27(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	migrations := GenerateMigrations()[:26]
+
+	migrates := migrate.NewMigrate(db.Client().Database("test"), migrations...)
+	err := migrates.Up(migrate.AllAvailable)
+	assert.NoError(t, err)
+
+	namespace := models.Namespace{
+		Name:     "namespace",
+		Owner:    "owner",
+		TenantID: "tenant",
+		Settings: &models.NamespaceSettings{SessionRecord: true},
+	}
+
+	_, err = db.Client().Database("test").Collection("namespaces").InsertOne(context.TODO(), namespace)
+	assert.NoError(t, err)
+
+	migrations = GenerateMigrations()[:27]
+
+	migrates = migrate.NewMigrate(db.Client().Database("test"), migrations...)
+	err = migrates.Up(migrate.AllAvailable)
+	assert.NoError(t, err)
+
+	version, _, err := migrates.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(27), version)
+
+	var migratedNamespace *models.Namespace
+	err = db.Client().Database("test").Collection("namespaces").FindOne(context.TODO(), bson.M{"tenant_id": namespace.TenantID}).Decode(&migratedNamespace)
+	assert.NoError(t, err)
+	assert.True(t, migratedNamespace.Settings.SessionRecord)
+	assert.True(t, migratedNamespace.Settings.AgentForwarding)
+}
@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/ssh"
+)
+
+var migration28 = migrate.Migration{
+	Version:     28,
+	Description: "Replace the MD5 fingerprints of the public and private keys by SHA256 fingerprints",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 28 - Up")
+
+		if err := migratePublicKeysFingerprint(db, ssh.FingerprintSHA256); err != nil {
+			return err
+		}
+
+		// The keys already migrated are skipped, since the collection is
+		// changed while it is iterated.
+		cursor, err := db.Collection("private_keys").Find(context.TODO(), bson.M{"legacy_fingerprint": bson.M{"$exists": false}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(context.TODO())
+
+		for cursor.Next(context.TODO()) {
+			key := new(models.PrivateKey)
+			if err := cursor.Decode(&key); err != nil {
+				return err
+			}
+
+			signer, err := ssh.ParsePrivateKey(key.Data)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"fingerprint": key.Fingerprint, "err": err}).Warning("Failed to parse private key")
+
+				continue
+			}
+
+			if _, err := db.Collection("private_keys").UpdateOne(context.TODO(), bson.M{"fingerprint": key.Fingerprint}, bson.M{"$set": bson.M{
+				"fingerprint":        ssh.FingerprintSHA256(signer.PublicKey()),
+				"legacy_fingerprint": key.Fingerprint,
+			}}); err != nil {
+				return err
+			}
+		}
+
+		return cursor.Err()
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 28 - Down")
+
+		if err := migratePublicKeysFingerprint(db, ssh.FingerprintLegacyMD5); err != nil {
+			return err
+		}
+
+		cursor, err := db.Collection("private_keys").Find(context.TODO(), bson.M{"legacy_fingerprint": bson.M{"$exists": true}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(context.TODO())
+
+		for cursor.Next(context.TODO()) {
+			key := new(models.PrivateKey)
+			if err := cursor.Decode(&key); err != nil {
+				return err
+			}
+
+			if _, err := db.Collection("private_keys").UpdateOne(context.TODO(), bson.M{"fingerprint": key.Fingerprint}, bson.M{
+				"$set":   bson.M{"fingerprint": key.LegacyFingerprint},
+				"$unset": bson.M{"legacy_fingerprint": ""},
+			}); err != nil {
+				return err
+			}
+		}
+
+		return cursor.Err()
+	},
+}
+
+// migratePublicKeysFingerprint sets the fingerprint of the public keys using
+// the fingerprint function.
+func migratePublicKeysFingerprint(db *mongo.Database, fingerprint func(ssh.PublicKey) string) error {
+	cursor, err := db.Collection("public_keys").Find(context.TODO(), bson.D{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		key := new(models.PublicKey)
+		if err := cursor.Decode(&key); err != nil {
+			return err
+		}
+
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey(key.Data) //nolint:dogsled
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"fingerprint": key.Fingerprint, "err": err}).Warning("Failed to parse public key")
+
+			continue
+		}
+
+		if _, err := db.Collection("public_keys").UpdateOne(context.TODO(), bson.M{"fingerprint": key.Fingerprint, "tenant_id": key.TenantID}, bson.M{"$set": bson.M{"fingerprint": fingerprint(pubKey)}}); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/shellhub-io/shellhub/api/pkg/dbtest"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/stretchr/testify/assert"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestMigration28(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	migrations := GenerateMigrations()[:27]
+
+	migrates := migrate.NewMigrate(db.Client().Database("test"), migrations...)
+	err := migrates.Up(migrate.AllAvailable)
+	assert.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	pubKey, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	publicKey := models.PublicKey{
+		Data:        ssh.MarshalAuthorizedKey(pubKey),
+		Fingerprint: ssh.FingerprintLegacyMD5(pubKey),
+		TenantID:    "tenant",
+	}
+
+	privateKey := models.PrivateKey{
+		Data: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}),
+		Fingerprint: ssh.FingerprintLegacyMD5(pubKey),
+	}
+
+	_, err = db.Client().Database("test").Collection("public_keys").InsertOne(context.TODO(), publicKey)
+	assert.NoError(t, err)
+
+	_, err = db.Client().Database("test").Collection("private_keys").InsertOne(context.TODO(), privateKey)
+	assert.NoError(t, err)
+
+	migrations = GenerateMigrations()[:28]
+
+	migrates = migrate.NewMigrate(db.Client().Database("test"), migrations...)
+	err = migrates.Up(migrate.AllAvailable)
+	assert.NoError(t, err)
+
+	version, _, err := migrates.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(28), version)
+
+	var migratedPublicKey *models.PublicKey
+	err = db.Client().Database("test").Collection("public_keys").FindOne(context.TODO(), bson.M{"tenant_id": "tenant"}).Decode(&migratedPublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.FingerprintSHA256(pubKey), migratedPublicKey.Fingerprint)
+
+	var migratedPrivateKey *models.PrivateKey
+	err = db.Client().Database("test").Collection("private_keys").FindOne(context.TODO(), bson.M{}).Decode(&migratedPrivateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.FingerprintSHA256(pubKey), migratedPrivateKey.Fingerprint)
+	assert.Equal(t, ssh.FingerprintLegacyMD5(pubKey), migratedPrivateKey.LegacyFingerprint)
+
+	err = migrates.Down(1)
+	assert.NoError(t, err)
+
+	err = db.Client().Database("test").Collection("public_keys").FindOne(context.TODO(), bson.M{"tenant_id": "tenant"}).Decode(&migratedPublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.FingerprintLegacyMD5(pubKey), migratedPublicKey.Fingerprint)
+
+	migratedPrivateKey = nil
+	err = db.Client().Database("test").Collection("private_keys").FindOne(context.TODO(), bson.M{}).Decode(&migratedPrivateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.FingerprintLegacyMD5(pubKey), migratedPrivateKey.Fingerprint)
+	assert.Empty(t, migratedPrivateKey.LegacyFingerprint)
+}
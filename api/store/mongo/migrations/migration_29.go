@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var migration29 = migrate.Migration{
+	Version:     29,
+	Description: "Create collection used to store the jobs executed on devices",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 29 - Up")
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"uid", 1}},
+			Options: options.Index().SetName("uid").SetUnique(true),
+		}
+		if _, err := db.Collection("jobs").Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+			return err
+		}
+
+		indexModel = mongo.IndexModel{
+			Keys:    bson.D{{"tenant_id", 1}},
+			Options: options.Index().SetName("tenant_id").SetUnique(false),
+		}
+		if _, err := db.Collection("jobs").Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 29 - Down")
+		if _, err := db.Collection("jobs").Indexes().DropOne(context.TODO(), "uid"); err != nil {
+			return err
+		}
+
+		if _, err := db.Collection("jobs").Indexes().DropOne(context.TODO(), "tenant_id"); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
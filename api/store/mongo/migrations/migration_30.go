@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var migration30 = migrate.Migration{
+	Version:     30,
+	Description: "Create collection used to store the break-glass logins of devices",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 30 - Up")
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"device_uid", 1}},
+			Options: options.Index().SetName("device_uid").SetUnique(false),
+		}
+		if _, err := db.Collection("break_glass_logins").Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 30 - Down")
+		if _, err := db.Collection("break_glass_logins").Indexes().DropOne(context.TODO(), "device_uid"); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
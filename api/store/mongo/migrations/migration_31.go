@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var migration31 = migrate.Migration{
+	Version:     31,
+	Description: "Create collection used to store the certificate authorities of the namespaces",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 31 - Up")
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"tenant_id", 1}},
+			Options: options.Index().SetName("tenant_id").SetUnique(true),
+		}
+		if _, err := db.Collection("certificate_authorities").Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 31 - Down")
+		if _, err := db.Collection("certificate_authorities").Indexes().DropOne(context.TODO(), "tenant_id"); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
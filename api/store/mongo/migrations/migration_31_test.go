@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shellhub-io/shellhub/api/pkg/dbtest"
+	"github.com/stretchr/testify/assert"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigration31(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	migrations := GenerateMigrations()[:31]
+
+	migrates := migrate.NewMigrate(db.Client().Database("test"), migrations...)
+	err := migrates.Up(migrate.AllAvailable)
+	assert.NoError(t, err)
+
+	version, _, err := migrates.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(31), version)
+
+	cursor, err := db.Client().Database("test").Collection("certificate_authorities").Indexes().List(context.TODO())
+	assert.NoError(t, err)
+
+	var results []bson.M
+	err = cursor.All(context.TODO(), &results)
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, index := range results {
+		names = append(names, index["name"].(string))
+	}
+
+	assert.Contains(t, names, "tenant_id")
+
+	err = migrates.Down(1)
+	assert.NoError(t, err)
+
+	cursor, err = db.Client().Database("test").Collection("certificate_authorities").Indexes().List(context.TODO())
+	assert.NoError(t, err)
+
+	results = nil
+	err = cursor.All(context.TODO(), &results)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	migrate "github.com/xakep666/mongo-migrate"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var migration32 = migrate.Migration{
+	Version:     32,
+	Description: "Create the index used to look up the certificate authorities by fingerprint",
+	Up: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 32 - Up")
+		indexModel := mongo.IndexModel{
+			Keys:    bson.D{{"fingerprint", 1}},
+			Options: options.Index().SetName("fingerprint").SetUnique(false),
+		}
+		if _, err := db.Collection("certificate_authorities").Indexes().CreateOne(context.TODO(), indexModel); err != nil {
+			return err
+		}
+
+		return nil
+	},
+	Down: func(db *mongo.Database) error {
+		logrus.Info("Applying migration 32 - Down")
+		if _, err := db.Collection("certificate_authorities").Indexes().DropOne(context.TODO(), "fingerprint"); err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
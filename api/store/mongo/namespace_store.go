@@ -268,3 +268,75 @@ func (s *Store) NamespaceGetSessionRecord(ctx context.Context, tenantID string)
 
 	return settings.Settings.SessionRecord, nil
 }
+
+func (s *Store) NamespaceSetPortForwarding(ctx context.Context, portForwarding bool, tenantID string) error {
+	if _, err := s.db.Collection("namespaces").UpdateOne(ctx, bson.M{"tenant_id": tenantID}, bson.M{"$set": bson.M{"settings.port_forwarding": portForwarding}}); err != nil {
+		return fromMongoError(err)
+	}
+
+	return nil
+}
+
+func (s *Store) NamespaceGetPortForwarding(ctx context.Context, tenantID string) (bool, error) {
+	var settings struct {
+		Settings *models.NamespaceSettings `json:"settings" bson:"settings"`
+	}
+
+	if err := s.db.Collection("namespaces").FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&settings); err != nil {
+		return false, fromMongoError(err)
+	}
+
+	if settings.Settings == nil {
+		return false, nil
+	}
+
+	return settings.Settings.PortForwarding, nil
+}
+
+func (s *Store) NamespaceSetAgentForwarding(ctx context.Context, agentForwarding bool, tenantID string) error {
+	if _, err := s.db.Collection("namespaces").UpdateOne(ctx, bson.M{"tenant_id": tenantID}, bson.M{"$set": bson.M{"settings.agent_forwarding": agentForwarding}}); err != nil {
+		return fromMongoError(err)
+	}
+
+	return nil
+}
+
+func (s *Store) NamespaceGetAgentForwarding(ctx context.Context, tenantID string) (bool, error) {
+	var settings struct {
+		Settings *models.NamespaceSettings `json:"settings" bson:"settings"`
+	}
+
+	if err := s.db.Collection("namespaces").FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&settings); err != nil {
+		return false, fromMongoError(err)
+	}
+
+	if settings.Settings == nil {
+		return false, nil
+	}
+
+	return settings.Settings.AgentForwarding, nil
+}
+
+func (s *Store) NamespaceSetMFA(ctx context.Context, mfa bool, tenantID string) error {
+	if _, err := s.db.Collection("namespaces").UpdateOne(ctx, bson.M{"tenant_id": tenantID}, bson.M{"$set": bson.M{"settings.mfa": mfa}}); err != nil {
+		return fromMongoError(err)
+	}
+
+	return nil
+}
+
+func (s *Store) NamespaceGetMFA(ctx context.Context, tenantID string) (bool, error) {
+	var settings struct {
+		Settings *models.NamespaceSettings `json:"settings" bson:"settings"`
+	}
+
+	if err := s.db.Collection("namespaces").FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&settings); err != nil {
+		return false, fromMongoError(err)
+	}
+
+	if settings.Settings == nil {
+		return false, nil
+	}
+
+	return settings.Settings.MFA, nil
+}
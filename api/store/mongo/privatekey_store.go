@@ -15,7 +15,8 @@ func (s *Store) PrivateKeyCreate(ctx context.Context, key *models.PrivateKey) er
 
 func (s *Store) PrivateKeyGet(ctx context.Context, fingerprint string) (*models.PrivateKey, error) {
 	privKey := new(models.PrivateKey)
-	if err := s.db.Collection("private_keys").FindOne(ctx, bson.M{"fingerprint": fingerprint}).Decode(&privKey); err != nil {
+	query := bson.M{"$or": []bson.M{{"fingerprint": fingerprint}, {"legacy_fingerprint": fingerprint}}}
+	if err := s.db.Collection("private_keys").FindOne(ctx, query).Decode(&privKey); err != nil {
 		return nil, fromMongoError(err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/clock"
 	"github.com/shellhub-io/shellhub/pkg/models"
@@ -142,6 +143,25 @@ func (s *Store) SessionSetRecorded(ctx context.Context, uid models.UID, recorded
 	return fromMongoError(err)
 }
 
+func (s *Store) SessionCreateForward(ctx context.Context, uid models.UID, forward *models.SessionForward) error {
+	_, err := s.db.Collection("sessions").UpdateOne(ctx, bson.M{"uid": uid}, bson.M{"$push": bson.M{"forwards": forward}})
+
+	return fromMongoError(err)
+}
+
+func (s *Store) SessionSetState(ctx context.Context, uid models.UID, state string) error {
+	res, err := s.db.Collection("sessions").UpdateOne(ctx, bson.M{"uid": uid}, bson.M{"$set": bson.M{"state": state}})
+	if err != nil {
+		return fromMongoError(err)
+	}
+
+	if res.MatchedCount < 1 {
+		return store.ErrNoDocuments
+	}
+
+	return nil
+}
+
 func (s *Store) SessionCreate(ctx context.Context, session models.Session) (*models.Session, error) {
 	session.StartedAt = clock.Now()
 	session.LastSeen = session.StartedAt
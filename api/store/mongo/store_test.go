@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"testing"
 	"time"
 
@@ -537,6 +538,50 @@ func TestSetSessionRecorded(t *testing.T) {
 	assert.Equal(t, returnedSession.Recorded, session.Recorded)
 }
 
+func TestSessionCreateForward(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+	namespace := models.Namespace{Name: "name", Owner: "owner", TenantID: "tenant"}
+
+	_, err := db.Client().Database("test").Collection("namespaces").InsertOne(ctx, namespace)
+	assert.NoError(t, err)
+
+	device := models.Device{
+		UID:      "uid",
+		Identity: &models.DeviceIdentity{MAC: "mac"},
+		TenantID: "tenant",
+		LastSeen: time.Now(),
+	}
+
+	err = mongostore.DeviceCreate(ctx, device, "")
+	assert.NoError(t, err)
+
+	session := models.Session{
+		Username:  "user",
+		UID:       "uid",
+		DeviceUID: models.UID(device.UID),
+		IPAddress: "0.0.0.0",
+	}
+
+	_, err = mongostore.SessionCreate(ctx, session)
+	assert.NoError(t, err)
+
+	forward := &models.SessionForward{Type: models.SessionForwardLocal, Host: "localhost", Port: 8080, CreatedAt: clock.Now()}
+
+	err = mongostore.SessionCreateForward(ctx, models.UID(session.UID), forward)
+	assert.NoError(t, err)
+
+	returnedSession, err := mongostore.SessionGet(ctx, models.UID(session.UID))
+	assert.NoError(t, err)
+	assert.Len(t, returnedSession.Forwards, 1)
+	assert.Equal(t, forward.Type, returnedSession.Forwards[0].Type)
+	assert.Equal(t, forward.Host, returnedSession.Forwards[0].Host)
+	assert.Equal(t, forward.Port, returnedSession.Forwards[0].Port)
+}
+
 func TestKeepAliveSession(t *testing.T) {
 	db := dbtest.DBServer{}
 	defer db.Stop()
@@ -843,6 +888,48 @@ func TestGetDeviceByMac(t *testing.T) {
 	assert.NotEmpty(t, d)
 }
 
+func TestGetDeviceByIdentity(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+	namespace := models.Namespace{Name: "name", Owner: "owner", TenantID: "tenant"}
+
+	_, err := db.Client().Database("test").Collection("namespaces").InsertOne(ctx, namespace)
+	assert.NoError(t, err)
+
+	authReq := &models.DeviceAuthRequest{
+		DeviceAuth: &models.DeviceAuth{
+			TenantID: "tenant",
+			Identity: &models.DeviceIdentity{
+				Source: models.DeviceIdentitySourceMachineID,
+				ID:     "machine_id",
+			},
+		},
+	}
+
+	uid := sha256.Sum256(structhash.Dump(authReq.DeviceAuth, 1))
+
+	device := models.Device{
+		UID:      hex.EncodeToString(uid[:]),
+		Identity: authReq.Identity,
+		TenantID: authReq.TenantID,
+		LastSeen: clock.Now(),
+	}
+
+	err = mongostore.DeviceCreate(ctx, device, "")
+	assert.NoError(t, err)
+
+	d, err := mongostore.DeviceGetByIdentity(ctx, models.DeviceIdentitySourceMachineID, "machine_id", "tenant", "pending")
+	assert.NoError(t, err)
+	assert.Equal(t, device.UID, d.UID)
+	assert.Equal(t, device.UID[:12], d.Name)
+
+	_, err = mongostore.DeviceGetByIdentity(ctx, models.DeviceIdentitySourceProductUUID, "machine_id", "tenant", "pending")
+	assert.Equal(t, store.ErrNoDocuments, err)
+}
+
 func TestSessionDeleteRecordFrame(t *testing.T) {
 	db := dbtest.DBServer{}
 	defer db.Stop()
@@ -1179,6 +1266,58 @@ func TestListDevices(t *testing.T) {
 	assert.NotEmpty(t, devices)
 }
 
+func TestListDevicesFilterInventory(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+	namespace := models.Namespace{Name: "name", Owner: "owner", TenantID: "tenant"}
+
+	_, err := db.Client().Database("test").Collection("namespaces").InsertOne(ctx, namespace)
+	assert.NoError(t, err)
+
+	for i, memory := range []uint64{1 << 30, 8 << 30} {
+		device := models.Device{
+			UID:      fmt.Sprintf("uid%d", i),
+			Identity: &models.DeviceIdentity{MAC: fmt.Sprintf("mac%d", i)},
+			TenantID: "tenant",
+			LastSeen: clock.Now(),
+			Info: &models.DeviceInfo{
+				Kernel: fmt.Sprintf("5.%d.0-generic", i),
+				Memory: memory,
+			},
+		}
+
+		err = mongostore.DeviceCreate(ctx, device, "")
+		assert.NoError(t, err)
+	}
+
+	filters := []models.Filter{
+		{
+			Type:   "property",
+			Params: &models.PropertyParams{Name: "info.memory", Operator: "gt", Value: float64(4 << 30)},
+		},
+	}
+
+	devices, count, err := mongostore.DeviceList(ctx, paginator.Query{Page: -1, PerPage: -1}, filters, "", "last_seen", "asc")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "uid1", devices[0].UID)
+
+	filters = []models.Filter{
+		{
+			Type:   "property",
+			Params: &models.PropertyParams{Name: "info.kernel", Operator: "like", Value: "^5.0"},
+		},
+	}
+
+	devices, count, err = mongostore.DeviceList(ctx, paginator.Query{Page: -1, PerPage: -1}, filters, "", "last_seen", "asc")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "uid0", devices[0].UID)
+}
+
 func TestListFirewallRules(t *testing.T) {
 	db := dbtest.DBServer{}
 	defer db.Stop()
@@ -1847,6 +1986,64 @@ func TestUpdateNamespace(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestNamespacePortForwarding(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+
+	_, err := mongostore.NamespaceCreate(ctx, &models.Namespace{
+		Name:       "namespace",
+		Owner:      "owner",
+		TenantID:   "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
+		Members:    []interface{}{"owner"},
+		Settings:   &models.NamespaceSettings{SessionRecord: true},
+		MaxDevices: -1,
+	})
+	assert.NoError(t, err)
+
+	status, err := mongostore.NamespaceGetPortForwarding(ctx, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+	assert.False(t, status)
+
+	err = mongostore.NamespaceSetPortForwarding(ctx, true, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+
+	status, err = mongostore.NamespaceGetPortForwarding(ctx, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+	assert.True(t, status)
+}
+
+func TestNamespaceAgentForwarding(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+
+	_, err := mongostore.NamespaceCreate(ctx, &models.Namespace{
+		Name:       "namespace",
+		Owner:      "owner",
+		TenantID:   "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
+		Members:    []interface{}{"owner"},
+		Settings:   &models.NamespaceSettings{SessionRecord: true},
+		MaxDevices: -1,
+	})
+	assert.NoError(t, err)
+
+	status, err := mongostore.NamespaceGetAgentForwarding(ctx, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+	assert.False(t, status)
+
+	err = mongostore.NamespaceSetAgentForwarding(ctx, true, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+
+	status, err = mongostore.NamespaceGetAgentForwarding(ctx, "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+	assert.NoError(t, err)
+	assert.True(t, status)
+}
+
 func TestRemoveNamespaceUser(t *testing.T) {
 	db := dbtest.DBServer{}
 	defer db.Stop()
@@ -2057,3 +2254,96 @@ func TestDeletePublicKey(t *testing.T) {
 	err = mongostore.PublicKeyDelete(ctx, newKey.Fingerprint, newKey.TenantID)
 	assert.NoError(t, err)
 }
+
+func TestJobCreateAndUpdateResult(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+
+	job := &models.Job{
+		UID:       "job",
+		TenantID:  "tenant",
+		Command:   "uptime",
+		CreatedBy: "username",
+		CreatedAt: clock.Now(),
+		Results: []models.JobResult{
+			{Device: "uid1", Status: models.JobStatusPending},
+			{Device: "uid2", Status: models.JobStatusPending},
+		},
+		Audit: []models.JobAuditEntry{{Time: clock.Now(), Action: "created", Actor: "username"}},
+	}
+
+	err := mongostore.JobCreate(ctx, job)
+	assert.NoError(t, err)
+
+	result := &models.JobResult{Device: "uid2", Status: models.JobStatusFinished, Stdout: "up", ExitCode: 1}
+	err = mongostore.JobUpdateResult(ctx, models.UID(job.UID), result, &models.JobAuditEntry{Time: clock.Now(), Action: "finished", Device: "uid2"})
+	assert.NoError(t, err)
+
+	err = mongostore.JobUpdateResult(ctx, models.UID(job.UID), &models.JobResult{Device: "uid3"}, &models.JobAuditEntry{})
+	assert.EqualError(t, err, store.ErrNoDocuments.Error())
+
+	stored, err := mongostore.JobGet(ctx, models.UID(job.UID))
+	assert.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Results[0].Status)
+	assert.Equal(t, "up", stored.Results[1].Stdout)
+	assert.Equal(t, 1, stored.Results[1].ExitCode)
+	assert.Len(t, stored.Audit, 2)
+
+	jobs, count, err := mongostore.JobList(ctx, paginator.Query{Page: -1, PerPage: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Len(t, jobs, 1)
+}
+
+func TestBreakGlassLoginCreateAndList(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+
+	logins := []models.BreakGlassLogin{
+		{DeviceUID: "uid", TenantID: "tenant", Username: "root", Fingerprint: "fingerprint", IPAddress: "192.168.1.10", LoggedAt: clock.Now()},
+		{DeviceUID: "uid", TenantID: "tenant", Username: "admin", Fingerprint: "fingerprint", IPAddress: "192.168.1.11", LoggedAt: clock.Now()},
+		{DeviceUID: "uid2", TenantID: "tenant", Username: "root", Fingerprint: "fingerprint", IPAddress: "192.168.1.12", LoggedAt: clock.Now()},
+	}
+
+	err := mongostore.BreakGlassLoginCreate(ctx, logins)
+	assert.NoError(t, err)
+
+	list, count, err := mongostore.BreakGlassLoginList(ctx, models.UID("uid"), paginator.Query{Page: -1, PerPage: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, list, 2)
+}
+
+func TestCertificateAuthorityCreateAndGet(t *testing.T) {
+	db := dbtest.DBServer{}
+	defer db.Stop()
+
+	ctx := context.TODO()
+	mongostore := NewStore(db.Client().Database("test"), cache.NewNullCache())
+
+	ca := &models.CertificateAuthority{TenantID: "tenant", PublicKey: []byte("public"), Fingerprint: "fingerprint", PrivateKey: []byte("private"), CreatedAt: clock.Now()}
+
+	err := mongostore.CertificateAuthorityCreate(ctx, ca)
+	assert.NoError(t, err)
+
+	returned, err := mongostore.CertificateAuthorityGet(ctx, ca.TenantID)
+	assert.NoError(t, err)
+	assert.Equal(t, ca.PublicKey, returned.PublicKey)
+	assert.Equal(t, ca.PrivateKey, returned.PrivateKey)
+
+	_, err = mongostore.CertificateAuthorityGet(ctx, "other")
+	assert.Equal(t, store.ErrNoDocuments, err)
+
+	returned, err = mongostore.CertificateAuthorityGetByFingerprint(ctx, ca.Fingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, ca.TenantID, returned.TenantID)
+
+	_, err = mongostore.CertificateAuthorityGetByFingerprint(ctx, "other")
+	assert.Equal(t, store.ErrNoDocuments, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/shellhub-io/shellhub/api/apicontext"
+	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/pkg/api/paginator"
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -286,6 +287,48 @@ func (s *Store) UserUpdateAccountStatus(ctx context.Context, id string) error {
 	return nil
 }
 
+func (s *Store) UserSetMFA(ctx context.Context, id, secret string, enabled bool) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.Collection("users").UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"mfa_secret": secret, "mfa_enabled": enabled}}); err != nil {
+		return fromMongoError(err)
+	}
+
+	return nil
+}
+
+// UserSetMFAStep records the time step of the last code accepted from the user,
+// failing with ErrNoDocuments when a code of the same or a later step was
+// already accepted, so concurrent replays are rejected too.
+func (s *Store) UserSetMFAStep(ctx context.Context, id string, step int64) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id": objID,
+		"$or": []bson.M{
+			{"mfa_step": bson.M{"$exists": false}},
+			{"mfa_step": bson.M{"$lt": step}},
+		},
+	}
+
+	res, err := s.db.Collection("users").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa_step": step}})
+	if err != nil {
+		return fromMongoError(err)
+	}
+
+	if res.MatchedCount < 1 {
+		return store.ErrNoDocuments
+	}
+
+	return nil
+}
+
 func (s *Store) UserDelete(ctx context.Context, id string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -71,20 +71,19 @@ func buildFilterQuery(filters []models.Filter) ([]bson.M, error) {
 
 				property = bson.M{"$eq": value}
 			case "gt":
-				var value int
-
-				switch v := params.Value.(type) {
-				case int:
-					value = v
-				case string:
-					var err error
-					value, err = strconv.Atoi(v)
-					if err != nil {
-						return nil, err
-					}
+				value, err := filterIntValue(params.Value)
+				if err != nil {
+					return nil, err
 				}
 
 				property = bson.M{"$gt": value}
+			case "lt":
+				value, err := filterIntValue(params.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				property = bson.M{"$lt": value}
 			}
 
 			queryFilter = append(queryFilter, bson.M{
@@ -121,6 +120,21 @@ func buildFilterQuery(filters []models.Filter) ([]bson.M, error) {
 	return queryMatch, nil
 }
 
+// filterIntValue converts the value of a numeric filter, which is a float64
+// when it is decoded from JSON.
+func filterIntValue(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	}
+
+	return 0, nil
+}
+
 // buildPaginationQuery builds a query with pagination to limit the number of returned results.
 func buildPaginationQuery(pagination paginator.Query) []bson.M {
 	if pagination.PerPage == -1 {
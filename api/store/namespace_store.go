@@ -20,4 +20,10 @@ type NamespaceStore interface {
 	NamespaceGetFirst(ctx context.Context, ID string) (*models.Namespace, error)
 	NamespaceSetSessionRecord(ctx context.Context, sessionRecord bool, tenantID string) error
 	NamespaceGetSessionRecord(ctx context.Context, tenantID string) (bool, error)
+	NamespaceSetPortForwarding(ctx context.Context, portForwarding bool, tenantID string) error
+	NamespaceGetPortForwarding(ctx context.Context, tenantID string) (bool, error)
+	NamespaceSetAgentForwarding(ctx context.Context, agentForwarding bool, tenantID string) error
+	NamespaceGetAgentForwarding(ctx context.Context, tenantID string) (bool, error)
+	NamespaceSetMFA(ctx context.Context, mfa bool, tenantID string) error
+	NamespaceGetMFA(ctx context.Context, tenantID string) (bool, error)
 }
@@ -19,4 +19,6 @@ type SessionStore interface {
 	SessionGetRecordFrame(ctx context.Context, uid models.UID) ([]models.RecordedSession, int, error)
 	SessionDeleteRecordFrame(ctx context.Context, uid models.UID) error
 	SessionSetRecorded(ctx context.Context, uid models.UID, recorded bool) error
+	SessionCreateForward(ctx context.Context, uid models.UID, forward *models.SessionForward) error
+	SessionSetState(ctx context.Context, uid models.UID, state string) error
 }
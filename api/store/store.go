@@ -22,4 +22,7 @@ type Store interface {
 	PrivateKeyStore
 	LicenseStore
 	StatsStore
+	JobStore
+	BreakGlassStore
+	CertificateStore
 }
@@ -21,5 +21,7 @@ type UserStore interface {
 	UserGetToken(ctx context.Context, ID string) (*models.UserTokenRecover, error)
 	UserDeleteTokens(ctx context.Context, ID string) error
 	UserUpdateAccountStatus(ctx context.Context, ID string) error
+	UserSetMFA(ctx context.Context, ID, secret string, enabled bool) error
+	UserSetMFAStep(ctx context.Context, ID string, step int64) error
 	UserDelete(ctx context.Context, ID string) error
 }
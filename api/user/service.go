@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 
+	"github.com/shellhub-io/shellhub/api/pkg/totp"
 	"github.com/shellhub-io/shellhub/api/store"
+	"github.com/shellhub-io/shellhub/pkg/clock"
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/shellhub-io/shellhub/pkg/validator"
 )
@@ -13,11 +15,19 @@ var (
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrConflict     = errors.New("conflict")
 	ErrBadRequest   = errors.New("bad request")
+	ErrInvalidCode  = errors.New("invalid verification code")
 )
 
+// mfaIssuer is the issuer of the TOTP secrets shown by the authenticator apps.
+const mfaIssuer = "ShellHub"
+
 type Service interface {
 	UpdateDataUser(ctx context.Context, data *models.User, id string) ([]validator.InvalidField, error)
 	UpdatePasswordUser(ctx context.Context, currentPassword, newPassword, id string) error
+	EnrollMFA(ctx context.Context, id string) (string, string, error)
+	EnableMFA(ctx context.Context, id, code string) error
+	DisableMFA(ctx context.Context, id, code string) error
+	AuthMFA(ctx context.Context, tenant, username, code string) error
 }
 
 type service struct {
@@ -70,3 +80,114 @@ func (s *service) UpdatePasswordUser(ctx context.Context, currentPassword, newPa
 
 	return ErrUnauthorized
 }
+
+// EnrollMFA generates a new TOTP secret for the user, returning it along with
+// its otpauth URL. The secret is only used to authenticate once the user
+// enables it with a valid code.
+func (s *service) EnrollMFA(ctx context.Context, id string) (string, string, error) {
+	user, _, err := s.store.UserGetByID(ctx, id, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	if user.MFAEnabled {
+		return "", "", ErrConflict
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.store.UserSetMFA(ctx, id, secret, false); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URL(mfaIssuer, user.Username, secret), nil
+}
+
+func (s *service) EnableMFA(ctx context.Context, id, code string) error {
+	user, _, err := s.store.UserGetByID(ctx, id, false)
+	if err != nil {
+		return err
+	}
+
+	if user.MFASecret == "" {
+		return ErrBadRequest
+	}
+
+	if !totp.Validate(user.MFASecret, code, clock.Now()) {
+		return ErrInvalidCode
+	}
+
+	return s.store.UserSetMFA(ctx, id, user.MFASecret, true)
+}
+
+func (s *service) DisableMFA(ctx context.Context, id, code string) error {
+	user, _, err := s.store.UserGetByID(ctx, id, false)
+	if err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled {
+		return ErrBadRequest
+	}
+
+	if !totp.Validate(user.MFASecret, code, clock.Now()) {
+		return ErrInvalidCode
+	}
+
+	return s.store.UserSetMFA(ctx, id, "", false)
+}
+
+// AuthMFA verifies the code of a member of the namespace, which is the second
+// factor of the SSH logins to the devices of the namespaces requiring it.
+func (s *service) AuthMFA(ctx context.Context, tenant, username, code string) error {
+	ns, err := s.store.NamespaceGet(ctx, tenant)
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return ErrUnauthorized
+		}
+
+		return err
+	}
+
+	user, err := s.store.UserGetByUsername(ctx, username)
+	if err != nil {
+		if err == store.ErrNoDocuments {
+			return ErrUnauthorized
+		}
+
+		return err
+	}
+
+	member := false
+	for _, id := range ns.Members {
+		if id == user.ID {
+			member = true
+
+			break
+		}
+	}
+
+	if !member || !user.MFAEnabled {
+		return ErrUnauthorized
+	}
+
+	step, ok := totp.ValidateStep(user.MFASecret, code, clock.Now())
+	if !ok || step <= user.MFAStep {
+		return ErrUnauthorized
+	}
+
+	// The code is rejected when replayed, even concurrently, until a code
+	// of a later step is accepted.
+	if err := s.store.UserSetMFAStep(ctx, user.ID, step); err != nil {
+		if err == store.ErrNoDocuments {
+			return ErrUnauthorized
+		}
+
+		return err
+	}
+
+	return nil
+}
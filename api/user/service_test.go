@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/shellhub-io/shellhub/api/pkg/totp"
 	"github.com/shellhub-io/shellhub/api/store"
 	"github.com/shellhub-io/shellhub/api/store/mocks"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	clockmocks "github.com/shellhub-io/shellhub/pkg/clock/mocks"
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/shellhub-io/shellhub/pkg/validator"
 	"github.com/stretchr/testify/assert"
+	mocklib "github.com/stretchr/testify/mock"
 )
 
 func TestUpdateDataUser(t *testing.T) {
@@ -145,3 +150,293 @@ func TestUpdatePasswordUser(t *testing.T) {
 
 	mock.AssertExpectations(t)
 }
+
+func TestEnrollMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	user := &models.User{Name: "name", Username: "username", ID: "id"}
+	enabled := &models.User{Name: "name", Username: "username", ID: "id", MFAEnabled: true, MFASecret: "GEZDGNBVGY3TQOJQ"}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		expected      error
+	}{
+		{
+			name: "EnrollMFA fails when the user is not found",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(nil, 0, Err).Once()
+			},
+			expected: Err,
+		},
+		{
+			name: "EnrollMFA fails when MFA is already enabled",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(enabled, 0, nil).Once()
+			},
+			expected: ErrConflict,
+		},
+		{
+			name: "EnrollMFA succeeds",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("UserSetMFA", ctx, user.ID, mocklib.AnythingOfType("string"), false).Return(nil).Once()
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			secret, url, err := s.EnrollMFA(ctx, user.ID)
+			assert.Equal(t, tc.expected, err)
+			if err == nil {
+				assert.NotEmpty(t, secret)
+				assert.Contains(t, url, secret)
+			}
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestEnableMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(t, err)
+
+	user := &models.User{Name: "name", Username: "username", ID: "id", MFASecret: secret}
+	notEnrolled := &models.User{Name: "name", Username: "username", ID: "id"}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		code          string
+		expected      error
+	}{
+		{
+			name: "EnableMFA fails when the user has not enrolled",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(notEnrolled, 0, nil).Once()
+			},
+			code:     code,
+			expected: ErrBadRequest,
+		},
+		{
+			name: "EnableMFA fails when the code is invalid",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+			},
+			code:     "invalid",
+			expected: ErrInvalidCode,
+		},
+		{
+			name: "EnableMFA succeeds",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("UserSetMFA", ctx, user.ID, secret, true).Return(nil).Once()
+			},
+			code:     code,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			assert.Equal(t, tc.expected, s.EnableMFA(ctx, user.ID, tc.code))
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestDisableMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(t, err)
+
+	user := &models.User{Name: "name", Username: "username", ID: "id", MFAEnabled: true, MFASecret: secret}
+	disabled := &models.User{Name: "name", Username: "username", ID: "id"}
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		code          string
+		expected      error
+	}{
+		{
+			name: "DisableMFA fails when MFA is not enabled",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(disabled, 0, nil).Once()
+			},
+			code:     code,
+			expected: ErrBadRequest,
+		},
+		{
+			name: "DisableMFA fails when the code is invalid",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+			},
+			code:     "invalid",
+			expected: ErrInvalidCode,
+		},
+		{
+			name: "DisableMFA succeeds",
+			requiredMocks: func() {
+				mock.On("UserGetByID", ctx, user.ID, false).Return(user, 0, nil).Once()
+				mock.On("UserSetMFA", ctx, user.ID, "", false).Return(nil).Once()
+			},
+			code:     code,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			assert.Equal(t, tc.expected, s.DisableMFA(ctx, user.ID, tc.code))
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
+
+func TestAuthMFA(t *testing.T) {
+	mock := &mocks.Store{}
+	s := NewService(store.Store(mock))
+
+	ctx := context.TODO()
+
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	clockMock := &clockmocks.Clock{}
+	clock.DefaultBackend = clockMock
+	clockMock.On("Now").Return(func() time.Time { return now })
+
+	code, err := totp.Code(secret, now)
+	assert.NoError(t, err)
+
+	step := now.Unix() / totp.Period
+
+	user := &models.User{Name: "name", Username: "username", ID: "id", MFAEnabled: true, MFASecret: secret}
+	replayed := &models.User{Name: "name", Username: "username", ID: "id", MFAEnabled: true, MFASecret: secret, MFAStep: step}
+	disabled := &models.User{Name: "name", Username: "username", ID: "id"}
+	namespace := &models.Namespace{Name: "namespace", Owner: "owner", TenantID: "tenant", Members: []interface{}{"owner", user.ID}}
+	other := &models.Namespace{Name: "other", Owner: "owner", TenantID: "tenant", Members: []interface{}{"owner"}}
+
+	Err := errors.New("error")
+
+	cases := []struct {
+		name          string
+		requiredMocks func()
+		code          string
+		expected      error
+	}{
+		{
+			name: "AuthMFA fails when the namespace is not found",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(nil, store.ErrNoDocuments).Once()
+			},
+			code:     code,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA fails when the user is not found",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(nil, Err).Once()
+			},
+			code:     code,
+			expected: Err,
+		},
+		{
+			name: "AuthMFA fails when the user is not a member of the namespace",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(other, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(user, nil).Once()
+			},
+			code:     code,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA fails when the user has not enabled MFA",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(disabled, nil).Once()
+			},
+			code:     code,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA fails when the code is invalid",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(user, nil).Once()
+			},
+			code:     "invalid",
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA fails when the code was already accepted",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(replayed, nil).Once()
+			},
+			code:     code,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA fails when the code is accepted concurrently",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(user, nil).Once()
+				mock.On("UserSetMFAStep", ctx, user.ID, step).Return(store.ErrNoDocuments).Once()
+			},
+			code:     code,
+			expected: ErrUnauthorized,
+		},
+		{
+			name: "AuthMFA succeeds",
+			requiredMocks: func() {
+				mock.On("NamespaceGet", ctx, namespace.TenantID).Return(namespace, nil).Once()
+				mock.On("UserGetByUsername", ctx, user.Username).Return(user, nil).Once()
+				mock.On("UserSetMFAStep", ctx, user.ID, step).Return(nil).Once()
+			},
+			code:     code,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.requiredMocks()
+			assert.Equal(t, tc.expected, s.AuthMFA(ctx, namespace.TenantID, user.Username, tc.code))
+		})
+	}
+
+	mock.AssertExpectations(t)
+}
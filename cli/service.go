@@ -100,7 +100,8 @@ func (s *service) NamespaceCreate(data Arguments) (*models.Namespace, error) {
 		TenantID: tenantID,
 		Members:  []interface{}{usr.ID},
 		Settings: &models.NamespaceSettings{
-			SessionRecord: true,
+			SessionRecord:   true,
+			AgentForwarding: true,
 		},
 	})
 	if err != nil {
@@ -178,7 +178,8 @@ func initData(dataNeeded string) Data {
 		TenantID: "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
 		Members:  []interface{}{"1"},
 		Settings: &models.NamespaceSettings{
-			SessionRecord: true,
+			SessionRecord:   true,
+			AgentForwarding: true,
 		},
 	}
 
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/parnurzeal/gorequest"
@@ -23,7 +24,10 @@ const (
 var (
 	ErrConnectionFailed = errors.New("connection failed")
 	ErrNotFound         = errors.New("not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrUnauthorized     = errors.New("unauthorized")
 	ErrUnknown          = errors.New("unknown error")
+	ErrUnsupportedProxy = errors.New("unsupported proxy scheme")
 )
 
 func NewClient(opts ...Opt) Client {
@@ -44,10 +48,12 @@ func NewClient(opts ...Opt) Client {
 	httpClient.Client = retryClient.StandardClient()
 
 	c := &client{
-		host:   apiHost,
-		port:   apiPort,
-		scheme: apiScheme,
-		http:   httpClient,
+		host:    apiHost,
+		port:    apiPort,
+		scheme:  apiScheme,
+		http:    httpClient,
+		proxy:   http.ProxyFromEnvironment,
+		retries: retryClient.RetryMax,
 	}
 
 	for _, opt := range opts {
@@ -56,10 +62,17 @@ func NewClient(opts ...Opt) Client {
 		}
 	}
 
+	retryClient.RetryMax = c.retries
+	httpClient.Client.Timeout = c.timeout
+
 	if c.logger != nil {
 		retryClient.Logger = &LeveledLogger{c.logger}
 	}
 
+	c.transport = http.DefaultTransport.(*http.Transport).Clone()
+	c.transport.Proxy = c.proxy
+	retryClient.HTTPClient.Transport = c.transport
+
 	return c
 }
 
@@ -74,6 +87,16 @@ type client struct {
 	port   int
 	http   *gorequest.SuperAgent
 	logger *logrus.Logger
+	proxy  func(*http.Request) (*url.URL, error)
+
+	// retries is the maximum number of retries of a failed request and
+	// timeout the time limit of a request with its retries, if not zero.
+	retries int
+	timeout time.Duration
+
+	// transport is shared by the requests to the server and the ones to
+	// other hosts made on behalf of the client.
+	transport *http.Transport
 }
 
 func (c *client) ListDevices() ([]models.Device, error) {
@@ -104,8 +127,9 @@ func (c *client) GetDevice(uid string) (*models.Device, error) {
 }
 
 func buildURL(c *client, uri string) string {
-	u, _ := url.Parse(fmt.Sprintf("%s://%s:%d", c.scheme, c.host, c.port))
-	u.Path = path.Join(u.Path, uri)
+	// The uri is parsed as an escaped path, so its segments may carry
+	// escaped slashes (e.g. SHA256 fingerprints).
+	u, _ := url.Parse(fmt.Sprintf("%s://%s:%d%s", c.scheme, c.host, c.port, path.Join("/", uri)))
 
 	return u.String()
 }
@@ -5,6 +5,7 @@ package client
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"go.uber.org/multierr"
@@ -24,6 +25,7 @@ type Client interface {
 type internalAPI interface {
 	LookupDevice()
 	GetPublicKey(fingerprint, tenant string) (*models.PublicKey, error)
+	LookupPublicKey(fingerprint string) (*models.PublicKey, error)
 	CreatePrivateKey() (*models.PrivateKey, error)
 	EvaluateKey(fingerprint string, dev *models.Device) (bool, error)
 	DevicesOffline(id string) error
@@ -31,8 +33,16 @@ type internalAPI interface {
 	PatchSessions(uid string) []error
 	FinishSession(uid string) []error
 	RecordSession(session *models.SessionRecorded, recordURL string)
+	CreateSessionForward(uid string, forward *models.SessionForward) error
+	SetSessionState(uid, state string) error
+	GetNamespace(tenant string) (*models.Namespace, error)
 	Lookup(lookup map[string]string) (string, []error)
 	DeviceLookup(lookup map[string]string) (*models.Device, []error)
+	AuthMFA(tenant, username, code string) error
+	GetCertificateAuthority(tenant string) (*models.CertificateAuthority, error)
+	LookupCertificateAuthority(fingerprint string) (*models.CertificateAuthority, error)
+	CheckAuthLimit(attempt *models.AuthAttempt) (*models.AuthLimit, error)
+	ReportAuthFailure(attempt *models.AuthAttempt) (*models.AuthLimit, error)
 }
 
 func (c *client) LookupDevice() {
@@ -40,7 +50,7 @@ func (c *client) LookupDevice() {
 
 func (c *client) GetPublicKey(fingerprint, tenant string) (*models.PublicKey, error) {
 	var pubKey *models.PublicKey
-	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/sshkeys/public-keys/%s/%s", fingerprint, tenant))).EndStruct(&pubKey)
+	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/sshkeys/public-keys/%s/%s", url.PathEscape(fingerprint), tenant))).EndStruct(&pubKey)
 	if len(errs) > 0 {
 		return nil, errs[0]
 	}
@@ -55,7 +65,7 @@ func (c *client) GetPublicKey(fingerprint, tenant string) (*models.PublicKey, er
 func (c *client) EvaluateKey(fingerprint string, dev *models.Device) (bool, error) {
 	var evaluate *bool
 
-	resp, _, errs := c.http.Post(buildURL(c, fmt.Sprintf("/internal/sshkeys/public-keys/evaluate/%s", fingerprint))).Send(dev).EndStruct(&evaluate)
+	resp, _, errs := c.http.Post(buildURL(c, fmt.Sprintf("/internal/sshkeys/public-keys/evaluate/%s", url.PathEscape(fingerprint)))).Send(dev).EndStruct(&evaluate)
 	if len(errs) > 0 {
 		var err error
 		for _, e := range errs {
@@ -72,6 +82,25 @@ func (c *client) EvaluateKey(fingerprint string, dev *models.Device) (bool, erro
 	return false, nil
 }
 
+// LookupPublicKey returns the public key with the fingerprint registered in
+// any namespace.
+func (c *client) LookupPublicKey(fingerprint string) (*models.PublicKey, error) {
+	var pubKey *models.PublicKey
+	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/sshkeys/public-keys/%s", url.PathEscape(fingerprint)))).EndStruct(&pubKey)
+	if len(errs) > 0 {
+		return nil, ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return pubKey, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, ErrUnknown
+	}
+}
+
 func (c *client) CreatePrivateKey() (*models.PrivateKey, error) {
 	var privKey *models.PrivateKey
 	_, _, errs := c.http.Post(buildURL(c, "/internal/sshkeys/private-keys")).EndStruct(&privKey)
@@ -117,6 +146,143 @@ func (c *client) RecordSession(session *models.SessionRecorded, recordURL string
 	c.http.Post(fmt.Sprintf("http://"+recordURL+"/internal/sessions/%s/record", session.UID)).Send(&session).End()
 }
 
+func (c *client) CreateSessionForward(uid string, forward *models.SessionForward) error {
+	resp, _, errs := c.http.Post(buildURL(c, fmt.Sprintf("/internal/sessions/%s/forwards", uid))).Send(forward).End()
+	if len(errs) > 0 {
+		return ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusForbidden:
+		return ErrForbidden
+	default:
+		return ErrUnknown
+	}
+}
+
+func (c *client) SetSessionState(uid, state string) error {
+	resp, _, errs := c.http.Patch(buildURL(c, fmt.Sprintf("/internal/sessions/%s/state", uid))).Send(map[string]string{
+		"state": state,
+	}).End()
+	if len(errs) > 0 {
+		return ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return ErrUnknown
+	}
+}
+
+func (c *client) GetNamespace(tenant string) (*models.Namespace, error) {
+	var namespace *models.Namespace
+	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/namespaces/%s", tenant))).EndStruct(&namespace)
+	if len(errs) > 0 {
+		return nil, ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return namespace, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+func (c *client) GetCertificateAuthority(tenant string) (*models.CertificateAuthority, error) {
+	var ca *models.CertificateAuthority
+	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/sshkeys/certificate-authority/%s", tenant))).EndStruct(&ca)
+	if len(errs) > 0 {
+		return nil, ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ca, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// LookupCertificateAuthority returns the certificate authority whose public key
+// has the fingerprint.
+func (c *client) LookupCertificateAuthority(fingerprint string) (*models.CertificateAuthority, error) {
+	var ca *models.CertificateAuthority
+	resp, _, errs := c.http.Get(buildURL(c, fmt.Sprintf("/internal/sshkeys/certificate-authorities/%s", url.PathEscape(fingerprint)))).EndStruct(&ca)
+	if len(errs) > 0 {
+		return nil, ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ca, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, ErrUnknown
+	}
+}
+
+// CheckAuthLimit returns the delay and the ban applied to the authentication
+// attempt.
+func (c *client) CheckAuthLimit(attempt *models.AuthAttempt) (*models.AuthLimit, error) {
+	return c.authLimit("/internal/auth/limits/check", attempt)
+}
+
+// ReportAuthFailure counts the failure of the authentication attempt,
+// returning the limit applied to the next ones.
+func (c *client) ReportAuthFailure(attempt *models.AuthAttempt) (*models.AuthLimit, error) {
+	return c.authLimit("/internal/auth/limits/failures", attempt)
+}
+
+func (c *client) authLimit(path string, attempt *models.AuthAttempt) (*models.AuthLimit, error) {
+	var limit *models.AuthLimit
+	resp, _, errs := c.http.Post(buildURL(c, path)).Send(attempt).EndStruct(&limit)
+	if len(errs) > 0 {
+		return nil, ErrConnectionFailed
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrUnknown
+	}
+
+	return limit, nil
+}
+
+// AuthMFA verifies the verification code of the user as the second factor of
+// an SSH login to a device of the namespace.
+func (c *client) AuthMFA(tenant, username, code string) error {
+	req := map[string]string{
+		"tenant_id": tenant,
+		"username":  username,
+		"code":      code,
+	}
+
+	resp, _, errs := c.http.Post(buildURL(c, "/internal/mfa/auth")).Send(req).End()
+	if len(errs) > 0 {
+		return ErrConnectionFailed
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	default:
+		return ErrUnknown
+	}
+}
+
 func (c *client) Lookup(lookup map[string]string) (string, []error) {
 	var device struct {
 		UID string `json:"uid"`
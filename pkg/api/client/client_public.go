@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/shellhub-io/shellhub/pkg/models"
@@ -21,6 +22,16 @@ const (
 	apiScheme = "https"
 )
 
+const (
+	// pingInterval is the interval of the pings sent to the server through
+	// the reverse connection.
+	pingInterval = 30 * time.Second
+
+	// pongTimeout is how long to wait for the reply of a ping before the
+	// reverse connection is considered lost.
+	pongTimeout = 15 * time.Second
+)
+
 type Client interface {
 	commonAPI
 	publicAPI
@@ -32,6 +43,9 @@ type publicAPI interface {
 	AuthDevice(req *models.DeviceAuthRequest) (*models.DeviceAuthResponse, error)
 	NewReverseListener(token string) (*revdial.Listener, error)
 	AuthPublicKey(req *models.PublicKeyAuthRequest, token string) (*models.PublicKeyAuthResponse, error)
+	GetBreakGlassKeys(token string) (*models.BreakGlassKeys, error)
+	ReportBreakGlassLogins(logins []models.BreakGlassLogin, token string) error
+	HTTPClient() *http.Client
 }
 
 func (c *client) GetInfo(agentVersion string) (*models.Info, error) {
@@ -70,14 +84,23 @@ func (c *client) NewReverseListener(token string) (*revdial.Listener, error) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	url := regexp.MustCompile(`^http`).ReplaceAllString(buildURL(c, "/ssh/connection"), "ws")
-	conn, _, err := websocket.DefaultDialer.Dial(url, req.Header)
+	conn, res, err := c.dialer().Dial(url, req.Header)
 	if err != nil {
+		if res != nil && (res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden) {
+			return nil, ErrUnauthorized
+		}
+
 		return nil, err
 	}
 
-	listener := revdial.NewListener(wsconnadapter.New(conn),
+	// The connection may become half-open (e.g. a NAT entry expired) without
+	// any error being reported, so it is checked by pings.
+	adapter := wsconnadapter.New(conn)
+	adapter.KeepAlive(pingInterval, pongTimeout)
+
+	listener := revdial.NewListener(adapter,
 		func(ctx context.Context, path string) (*websocket.Conn, *http.Response, error) {
-			return tunnelDial(ctx, strings.Replace(c.scheme, "http", "ws", 1), c.host, c.port, path)
+			return tunnelDial(ctx, c.dialer(), strings.Replace(c.scheme, "http", "ws", 1), c.host, c.port, path)
 		},
 	)
 
@@ -95,6 +118,48 @@ func (c *client) AuthPublicKey(req *models.PublicKeyAuthRequest, token string) (
 	return res, nil
 }
 
-func tunnelDial(ctx context.Context, protocol, address string, port int, path string) (*websocket.Conn, *http.Response, error) {
-	return websocket.DefaultDialer.DialContext(ctx, strings.Join([]string{fmt.Sprintf("%s://%s:%d", protocol, address, port), path}, ""), nil)
+func (c *client) GetBreakGlassKeys(token string) (*models.BreakGlassKeys, error) {
+	var keys *models.BreakGlassKeys
+	resp, _, errs := c.http.Get(buildURL(c, "/api/auth/ssh/keys")).Set("Authorization", fmt.Sprintf("Bearer %s", token)).EndStruct(&keys)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrUnauthorized
+	}
+
+	return keys, nil
+}
+
+func (c *client) ReportBreakGlassLogins(logins []models.BreakGlassLogin, token string) error {
+	resp, _, errs := c.http.Post(buildURL(c, "/api/devices/break-glass/logins")).Set("Authorization", fmt.Sprintf("Bearer %s", token)).Send(logins).End()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// dialer returns the websocket dialer, which goes through the same proxy as
+// the HTTP requests.
+func (c *client) dialer() *websocket.Dialer {
+	return &websocket.Dialer{
+		Proxy:            c.proxy,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+	}
+}
+
+// HTTPClient returns a HTTP client going through the same proxy as the requests
+// to the server, used to reach other hosts (e.g. the agent update URL).
+func (c *client) HTTPClient() *http.Client {
+	return &http.Client{Transport: c.transport}
+}
+
+func tunnelDial(ctx context.Context, dialer *websocket.Dialer, protocol, address string, port int, path string) (*websocket.Conn, *http.Response, error) {
+	return dialer.DialContext(ctx, strings.Join([]string{fmt.Sprintf("%s://%s:%d", protocol, address, port), path}, ""), nil)
 }
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -58,6 +60,25 @@ func WithPort(port int) Opt {
 	}
 }
 
+// WithRetries sets the maximum number of retries of a failed request, which is
+// retried indefinitely by default.
+func WithRetries(retries int) Opt {
+	return func(c *client) error {
+		c.retries = retries
+
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit of a request, including its retries.
+func WithTimeout(timeout time.Duration) Opt {
+	return func(c *client) error {
+		c.timeout = timeout
+
+		return nil
+	}
+}
+
 func WithLogger(logger *logrus.Logger) Opt {
 	return func(c *client) error {
 		c.logger = logger
@@ -65,3 +86,21 @@ func WithLogger(logger *logrus.Logger) Opt {
 		return nil
 	}
 }
+
+// WithProxy sets the proxy used to reach the server instead of the one set in
+// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables. HTTP proxies,
+// with the basic authentication credentials given in the URL, and SOCKS5
+// proxies are supported.
+func WithProxy(proxy *url.URL) Opt {
+	return func(c *client) error {
+		switch proxy.Scheme {
+		case "http", "socks5":
+		default:
+			return ErrUnsupportedProxy
+		}
+
+		c.proxy = http.ProxyURL(proxy)
+
+		return nil
+	}
+}
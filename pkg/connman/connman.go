@@ -30,12 +30,23 @@ func New() *ConnectionManager {
 }
 
 func (m *ConnectionManager) Set(key string, conn net.Conn) {
+	dialer := revdial.NewDialer(conn, "/ssh/revdial")
+
 	m.lock.Lock()
-	m.dialers[key] = revdial.NewDialer(conn, "/ssh/revdial")
+	m.dialers[key] = dialer
 	m.lock.Unlock()
 
 	go func() {
-		stats := TunnelStatus{id: key, online: m.dialers[key].IsOnline()}
+		stats := TunnelStatus{id: key, online: dialer.IsOnline()}
+
+		// The device may have reconnected in the meantime, so the dialer
+		// is only removed if it was not replaced.
+		m.lock.Lock()
+		if m.dialers[key] == dialer {
+			delete(m.dialers, key)
+		}
+		m.lock.Unlock()
+
 		m.status <- stats
 	}()
 }
@@ -53,6 +64,14 @@ func (m *ConnectionManager) Dial(ctx context.Context, key string) (net.Conn, err
 	return dialer.Dial(ctx)
 }
 
+// Len returns the number of devices connected.
+func (m *ConnectionManager) Len() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return len(m.dialers)
+}
+
 func (m *ConnectionManager) Online() (id string, online bool) {
 	status := <-m.status
 	id = status.id
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -32,9 +33,13 @@ type Tunnel struct {
 	ConnectionPath    string
 	DialerPath        string
 	ConnectionHandler func(*http.Request) (string, error)
-	connman           *connman.ConnectionManager
-	id                chan string
-	online            chan bool
+	// DialObserver, when set, is called after each dial to a device with
+	// the time it took and its error.
+	DialObserver func(id string, elapsed time.Duration, err error)
+
+	connman *connman.ConnectionManager
+	id      chan string
+	online  chan bool
 }
 
 func NewTunnel(connectionPath, dialerPath string) *Tunnel {
@@ -78,11 +83,18 @@ func (t *Tunnel) Router() http.Handler {
 }
 
 func (t *Tunnel) Dial(ctx context.Context, id string) (net.Conn, error) {
-	return t.connman.Dial(ctx, id)
+	start := time.Now()
+
+	conn, err := t.connman.Dial(ctx, id)
+	if t.DialObserver != nil {
+		t.DialObserver(id, time.Since(start), err)
+	}
+
+	return conn, err
 }
 
 func (t *Tunnel) SendRequest(ctx context.Context, id string, req *http.Request) (*http.Response, error) {
-	conn, err := t.connman.Dial(ctx, id)
+	conn, err := t.Dial(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +123,11 @@ func (t *Tunnel) ForwardResponse(resp *http.Response, w http.ResponseWriter) {
 	resp.Body.Close()
 }
 
+// Connections returns the number of devices connected to the tunnel.
+func (t *Tunnel) Connections() int {
+	return t.connman.Len()
+}
+
 func (t *Tunnel) Online() (id string, online bool) {
 	id, online = t.connman.Online()
 
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Kinds of the subjects whose authentication failures are limited.
+const (
+	AuthLimitIP     = "ip"
+	AuthLimitUser   = "user"
+	AuthLimitDevice = "device"
+)
+
+// AuthAttempt is an authentication attempt on the SSH gateway, whose failures
+// are counted for the source IP address, the login user of the device and the
+// device itself. Empty fields are not counted.
+type AuthAttempt struct {
+	IPAddress string `json:"ip_address"`
+	Username  string `json:"username"`
+	Device    string `json:"device"`
+}
+
+// AuthLimit is the limit applied to an authentication attempt.
+type AuthLimit struct {
+	// Delay is the time in seconds the attempt is delayed for.
+	Delay       int        `json:"delay"`
+	BannedUntil *time.Time `json:"banned_until,omitempty"`
+}
+
+// AuthBan is a subject temporarily banned from authenticating on the SSH
+// gateway after too many failures.
+type AuthBan struct {
+	Kind  string    `json:"kind"`
+	Value string    `json:"value"`
+	Until time.Time `json:"until"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BreakGlassKey is a public key of the namespace allowed to log into the
+// device through its local SSH listener.
+type BreakGlassKey struct {
+	Fingerprint string `json:"fingerprint"`
+	Data        []byte `json:"data"`
+	Hostname    string `json:"hostname,omitempty"`
+}
+
+// BreakGlassKeys is the set of public keys cached by the agent to accept
+// logins on its local SSH listener while the server is unreachable. It is
+// signed by the server, so the agent does not trust a modified copy.
+type BreakGlassKeys struct {
+	DeviceUID  string          `json:"device_uid"`
+	DeviceName string          `json:"device_name"`
+	TenantID   string          `json:"tenant_id"`
+	Keys       []BreakGlassKey `json:"keys"`
+	IssuedAt   time.Time       `json:"issued_at"`
+	// PortForwarding is the port forwarding setting of the namespace,
+	// enforced by the agent on the local listener.
+	PortForwarding bool `json:"port_forwarding,omitempty"`
+
+	// ServerKey is the PEM encoded public key used to verify the signature.
+	ServerKey string `json:"server_key,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// SignedData returns the data covered by the signature of the keys.
+func (k *BreakGlassKeys) SignedData() ([]byte, error) {
+	data := *k
+	data.ServerKey = ""
+	data.Signature = ""
+
+	return json.Marshal(data)
+}
+
+// BreakGlassLogin is a login made through the local SSH listener of a device,
+// reported by the agent once it reaches the server again.
+type BreakGlassLogin struct {
+	DeviceUID   string    `json:"device_uid" bson:"device_uid"`
+	TenantID    string    `json:"tenant_id" bson:"tenant_id"`
+	Username    string    `json:"username"`
+	Fingerprint string    `json:"fingerprint"`
+	IPAddress   string    `json:"ip_address" bson:"ip_address"`
+	LoggedAt    time.Time `json:"logged_at" bson:"logged_at"`
+}
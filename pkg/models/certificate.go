@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// CertificateAuthority is the SSH certificate authority of a namespace, which
+// signs the user certificates of its members.
+type CertificateAuthority struct {
+	TenantID string `json:"tenant_id" bson:"tenant_id"`
+	// PublicKey is the public key of the authority in the authorized_keys
+	// format, as trusted by the TrustedUserCAKeys option of OpenSSH.
+	PublicKey []byte `json:"public_key" bson:"public_key"`
+	// Fingerprint is the SHA256 fingerprint of the public key, which
+	// identifies the authority signing a certificate.
+	Fingerprint string    `json:"fingerprint" bson:"fingerprint"`
+	PrivateKey  []byte    `json:"-" bson:"private_key"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// CertificateRequest is the request of a user certificate for a public key.
+type CertificateRequest struct {
+	// PublicKey is the public key to certify in the authorized_keys format.
+	PublicKey []byte `json:"public_key"`
+	// Principals are the device users the certificate may log in as.
+	Principals []string `json:"principals"`
+	// Validity is the lifetime of the certificate in seconds.
+	Validity int `json:"validity"`
+	// Extensions are the permissions of the certificate (e.g.
+	// permit-port-forwarding).
+	Extensions []string `json:"extensions"`
+}
+
+// Certificate is a user certificate issued by the certificate authority of a
+// namespace.
+type Certificate struct {
+	// Data is the certificate in the authorized_keys format, as loaded by
+	// OpenSSH from the key-cert.pub file next to the private key.
+	Data        []byte    `json:"data"`
+	Serial      uint64    `json:"serial"`
+	KeyID       string    `json:"key_id"`
+	Principals  []string  `json:"principals"`
+	Extensions  []string  `json:"extensions"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+}
@@ -1,11 +1,17 @@
 package models
 
 import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+var ErrInvalidDevicePublicKey = errors.New("invalid device public key")
+
 type Device struct {
 	UID       string          `json:"uid"`
 	Name      string          `json:"name" bson:"name,omitempty" validate:"required,hostname_rfc1123,excludes=."`
@@ -17,6 +23,24 @@ type Device struct {
 	Online    bool            `json:"online" bson:",omitempty"`
 	Namespace string          `json:"namespace" bson:",omitempty"`
 	Status    string          `json:"status" bson:"status,omitempty" validate:"oneof=accepted rejected pending unused`
+
+	Connection *DeviceConnection `json:"connection,omitempty" bson:"connection,omitempty"`
+	Containers []DeviceContainer `json:"containers" bson:"containers"`
+}
+
+// HostKey parses the public key of the device, which is also the host key of
+// its SSH server. RSA keys are in PKCS #1 form and the others in PKIX form.
+func (d *Device) HostKey() (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(d.PublicKey))
+	if block == nil {
+		return nil, ErrInvalidDevicePublicKey
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
 }
 
 type DeviceAuthClaims struct {
@@ -27,8 +51,10 @@ type DeviceAuthClaims struct {
 }
 
 type DeviceAuthRequest struct {
-	Info     *DeviceInfo `json:"info"`
-	Sessions []string    `json:"sessions,omitempty"`
+	Info       *DeviceInfo       `json:"info"`
+	Sessions   []string          `json:"sessions,omitempty"`
+	Connection *DeviceConnection `json:"connection,omitempty"`
+	Containers []DeviceContainer `json:"containers,omitempty"`
 	*DeviceAuth
 }
 
@@ -46,8 +72,23 @@ type DeviceAuthResponse struct {
 	Namespace string `json:"namespace"`
 }
 
+const (
+	DeviceIdentitySourceMAC         = "mac"
+	DeviceIdentitySourceMachineID   = "machine-id"
+	DeviceIdentitySourceProductUUID = "product-uuid"
+	DeviceIdentitySourceCustom      = "custom"
+)
+
+// DeviceIdentity identifies the physical device across registrations.
+//
+// MAC is kept as the primary address of the device whenever it is available,
+// while Source and ID record the source chosen by the agent to identify the
+// device. Source and ID are not part of the device UID hash, so the UID of
+// the devices registered before they were introduced does not change.
 type DeviceIdentity struct {
-	MAC string `json:"mac"`
+	MAC    string `json:"mac"`
+	Source string `json:"source,omitempty" bson:"source,omitempty" hash:"-"`
+	ID     string `json:"id,omitempty" bson:"id,omitempty" hash:"-"`
 }
 
 type DeviceInfo struct {
@@ -56,6 +97,49 @@ type DeviceInfo struct {
 	Version    string `json:"version"`
 	Arch       string `json:"arch"`
 	Platform   string `json:"platform"`
+
+	// Inventory reported by the agent on each authorization. Memory and disk
+	// sizes are in bytes and the uptime is in seconds.
+	Kernel     string            `json:"kernel,omitempty" bson:"kernel,omitempty"`
+	CPU        *DeviceCPU        `json:"cpu,omitempty" bson:"cpu,omitempty"`
+	Memory     uint64            `json:"memory,omitempty" bson:"memory,omitempty"`
+	Disks      []DeviceDisk      `json:"disks,omitempty" bson:"disks,omitempty"`
+	Uptime     uint64            `json:"uptime,omitempty" bson:"uptime,omitempty"`
+	Interfaces []DeviceInterface `json:"interfaces,omitempty" bson:"interfaces,omitempty"`
+	Timezone   string            `json:"timezone,omitempty" bson:"timezone,omitempty"`
+}
+
+// DeviceConnection is the health of the connection between the agent and the
+// server, as reported by the agent. The connected time is in seconds.
+type DeviceConnection struct {
+	Reconnects    int    `json:"reconnects" bson:"reconnects"`
+	LastError     string `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	ConnectedTime uint64 `json:"connected_time" bson:"connected_time"`
+}
+
+// DeviceContainer is a running container on the device, which can be the
+// target of a session (e.g. user@namespace.device+container).
+type DeviceContainer struct {
+	ID     string `json:"id" bson:"id"`
+	Name   string `json:"name" bson:"name"`
+	Image  string `json:"image" bson:"image"`
+	Status string `json:"status" bson:"status"`
+}
+
+type DeviceCPU struct {
+	Model string `json:"model" bson:"model"`
+	Count int    `json:"count" bson:"count"`
+}
+
+type DeviceDisk struct {
+	Name string `json:"name" bson:"name"`
+	Size uint64 `json:"size" bson:"size"`
+}
+
+type DeviceInterface struct {
+	Name      string   `json:"name" bson:"name"`
+	MAC       string   `json:"mac,omitempty" bson:"mac,omitempty"`
+	Addresses []string `json:"addresses,omitempty" bson:"addresses,omitempty"`
 }
 
 type ConnectedDevice struct {
@@ -3,6 +3,7 @@ package models
 type Info struct {
 	Version   string    `json:"version"`
 	Endpoints Endpoints `json:"endpoints"`
+	UpdateURL string    `json:"update_url,omitempty"`
 }
 
 type Endpoints struct {
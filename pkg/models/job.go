@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	JobStatusPending  = "pending"
+	JobStatusRunning  = "running"
+	JobStatusFinished = "finished"
+	JobStatusFailed   = "failed"
+)
+
+// Job is a command executed on one or more devices of a namespace without an
+// interactive SSH session.
+type Job struct {
+	UID       string          `json:"uid"`
+	TenantID  string          `json:"tenant_id" bson:"tenant_id"`
+	Command   string          `json:"command"`
+	Timeout   int             `json:"timeout"`
+	Filter    string          `json:"filter,omitempty" bson:"filter,omitempty"`
+	CreatedBy string          `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time       `json:"created_at" bson:"created_at"`
+	Results   []JobResult     `json:"results"`
+	Audit     []JobAuditEntry `json:"audit"`
+}
+
+// JobResult is the outcome of a job in a device. Stdout and stderr are
+// truncated to JobOutputLimit bytes.
+type JobResult struct {
+	Device     UID       `json:"device"`
+	Status     string    `json:"status"`
+	Stdout     string    `json:"stdout"`
+	Stderr     string    `json:"stderr"`
+	ExitCode   int       `json:"exit_code" bson:"exit_code"`
+	Signal     string    `json:"signal,omitempty" bson:"signal,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+// JobAuditEntry records who did what on a job and when.
+type JobAuditEntry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Actor   string    `json:"actor,omitempty" bson:"actor,omitempty"`
+	Device  UID       `json:"device,omitempty" bson:"device,omitempty"`
+	Message string    `json:"message,omitempty" bson:"message,omitempty"`
+}
+
+// JobOutputLimit is the maximum size of the stdout and stderr of a job kept
+// for each device.
+const JobOutputLimit = 64 * 1024
+
+// JobCommand is the command of a job sent to the agent.
+type JobCommand struct {
+	Command string `json:"command"`
+	Timeout int    `json:"timeout"`
+}
+
+// JobOutput is the output of a job command returned by the agent.
+type JobOutput struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	Signal   string `json:"signal,omitempty"`
+}
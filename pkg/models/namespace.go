@@ -13,7 +13,10 @@ type Namespace struct {
 }
 
 type NamespaceSettings struct {
-	SessionRecord bool `json:"session_record" bson:"session_record,omitempty"`
+	SessionRecord   bool `json:"session_record" bson:"session_record,omitempty"`
+	PortForwarding  bool `json:"port_forwarding" bson:"port_forwarding,omitempty"`
+	AgentForwarding bool `json:"agent_forwarding" bson:"agent_forwarding,omitempty"`
+	MFA             bool `json:"mfa" bson:"mfa,omitempty"`
 }
 
 type Member struct {
@@ -3,7 +3,10 @@ package models
 import "time"
 
 type PrivateKey struct {
-	Data        []byte    `json:"data"`
-	Fingerprint string    `json:"fingerprint"`
-	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	Data        []byte `json:"data"`
+	Fingerprint string `json:"fingerprint"`
+	// LegacyFingerprint is the MD5 fingerprint of the key, which is used by
+	// the agents released before the SHA256 fingerprints.
+	LegacyFingerprint string    `json:"-" bson:"legacy_fingerprint,omitempty"`
+	CreatedAt         time.Time `json:"created_at" bson:"created_at"`
 }
@@ -5,19 +5,30 @@ import (
 )
 
 type Session struct {
-	UID           string    `json:"uid"`
-	DeviceUID     UID       `json:"device_uid,omitempty" bson:"device_uid"`
-	Device        *Device   `json:"device" bson:"device,omitempty"`
-	TenantID      string    `json:"tenant_id" bson:"tenant_id"`
-	Username      string    `json:"username"`
-	IPAddress     string    `json:"ip_address" bson:"ip_address"`
-	StartedAt     time.Time `json:"started_at" bson:"started_at"`
-	LastSeen      time.Time `json:"last_seen" bson:"last_seen"`
-	Active        bool      `json:"active" bson:",omitempty"`
-	Authenticated bool      `json:"authenticated" bson:"authenticated"`
-	Recorded      bool      `json:"recorded" bson:"recorded"`
+	UID           string           `json:"uid"`
+	DeviceUID     UID              `json:"device_uid,omitempty" bson:"device_uid"`
+	Device        *Device          `json:"device" bson:"device,omitempty"`
+	TenantID      string           `json:"tenant_id" bson:"tenant_id"`
+	Username      string           `json:"username"`
+	Container     string           `json:"container,omitempty" bson:"container,omitempty"`
+	IPAddress     string           `json:"ip_address" bson:"ip_address"`
+	StartedAt     time.Time        `json:"started_at" bson:"started_at"`
+	LastSeen      time.Time        `json:"last_seen" bson:"last_seen"`
+	Active        bool             `json:"active" bson:",omitempty"`
+	Authenticated bool             `json:"authenticated" bson:"authenticated"`
+	Recorded      bool             `json:"recorded" bson:"recorded"`
+	Forwards      []SessionForward `json:"forwards,omitempty" bson:"forwards,omitempty"`
+	Persistent    bool             `json:"persistent" bson:"persistent,omitempty"`
+	State         string           `json:"state,omitempty" bson:"state,omitempty"`
 }
 
+// States of the persistent sessions, which are kept alive on the device when
+// the user disconnects and can be reattached later.
+const (
+	SessionStateAttached = "attached"
+	SessionStateDetached = "detached"
+)
+
 type ActiveSession struct {
 	UID      UID       `json:"uid"`
 	LastSeen time.Time `json:"last_seen" bson:"last_seen"`
@@ -42,3 +53,26 @@ type SessionRecorded struct {
 	Width   int    `json:"width" bson:"width,omitempty"`
 	Height  int    `json:"height" bson:"height,omitempty"`
 }
+
+const (
+	SessionForwardLocal  = "local"
+	SessionForwardRemote = "remote"
+)
+
+// SessionForward describes a TCP port forwarding requested within a session.
+type SessionForward struct {
+	Type      string    `json:"type" bson:"type" validate:"required,oneof=local remote"`
+	Host      string    `json:"host" bson:"host"`
+	Port      uint32    `json:"port" bson:"port"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Restrictions of the sessions spliced by the gateway, used as a jump host,
+// to the SSH server of the agent, which enforces them since the gateway can
+// not see into the end-to-end encrypted session. They are named after the
+// authorized_keys options.
+const (
+	SessionRestrictPty             = "no-pty"
+	SessionRestrictPortForwarding  = "no-port-forwarding"
+	SessionRestrictAgentForwarding = "no-agent-forwarding"
+)
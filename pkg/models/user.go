@@ -14,6 +14,11 @@ type User struct {
 	Password      string `json:"password" bson:",omitempty"`
 	Namespaces    int    `json:"namespaces" bson:"namespaces,omitempty"`
 	Authenticated bool   `json:"Authenticated"`
+	MFAEnabled    bool   `json:"mfa_enabled" bson:"mfa_enabled,omitempty"`
+	MFASecret     string `json:"-" bson:"mfa_secret,omitempty"`
+	// MFAStep is the time step of the last code accepted, whose codes and
+	// the older ones are rejected when replayed.
+	MFAStep int64 `json:"-" bson:"mfa_step,omitempty"`
 }
 
 type UserAuthRequest struct {
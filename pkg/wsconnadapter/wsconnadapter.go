@@ -28,6 +28,31 @@ func New(conn *websocket.Conn) *Adapter {
 	}
 }
 
+// KeepAlive sends a ping every interval and fails the reads once a pong is not
+// received within the timeout, so a half-open connection is detected.
+func (a *Adapter) KeepAlive(interval, timeout time.Duration) {
+	extend := func() error {
+		return a.conn.SetReadDeadline(time.Now().Add(interval + timeout))
+	}
+
+	extend() // nolint:errcheck
+
+	a.conn.SetPongHandler(func(string) error {
+		return extend()
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := a.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+				return
+			}
+		}
+	}()
+}
+
 func (a *Adapter) Read(b []byte) (int, error) {
 	// Read() can be called concurrently, and we mutate some internal state here
 	a.readMutex.Lock()
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/clock"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// certificateContextKey holds the user certificate authenticating the
+// connection, whose extensions restrict what the connection is permitted.
+const certificateContextKey = "certificate"
+
+// Extensions of the user certificates enforced by the gateway.
+const (
+	permitPty             = "permit-pty"
+	permitPortForwarding  = "permit-port-forwarding"
+	permitAgentForwarding = "permit-agent-forwarding"
+)
+
+var (
+	ErrPtyNotPermitted             = errors.New("the certificate does not permit a terminal")
+	ErrAgentForwardingNotPermitted = errors.New("the certificate does not permit agent forwarding")
+)
+
+// authorizeJumpKey checks whether the public key of a jump host connection is
+// registered in a namespace, or the user certificate is signed by the
+// certificate authority of one, before the devices of its channels are known.
+func authorizeJumpKey(key ssh.PublicKey, user string) bool {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		return evaluateJumpCertificate(cert, user)
+	}
+
+	if _, err := client.NewClient().LookupPublicKey(ssh.FingerprintSHA256(key)); err != nil {
+		authFailures.WithLabelValues(authFailureUnknownKey).Inc()
+
+		return false
+	}
+
+	return true
+}
+
+// authorizeKey checks whether the public key, or the user certificate, is
+// allowed to log into the device as the user.
+func authorizeKey(key ssh.PublicKey, user string, device *models.Device) bool {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		return evaluateCertificate(cert, user, device)
+	}
+
+	return evaluateKey(ssh.FingerprintSHA256(key), device)
+}
+
+// evaluateCertificate checks whether the user certificate is signed by the
+// certificate authority of the namespace of the device, is currently valid and
+// lists the user among its principals.
+func evaluateCertificate(cert *ssh.Certificate, user string, device *models.Device) bool {
+	log := certificateLog(cert, user).WithField("device", device.UID)
+
+	ca, err := client.NewClient().GetCertificateAuthority(device.TenantID)
+	if err != nil {
+		log.WithError(err).Error("Failed to get the certificate authority")
+		authFailures.WithLabelValues(authFailureInvalidCertificate).Inc()
+
+		return false
+	}
+
+	return checkCertificate(cert, user, ca, log)
+}
+
+// evaluateJumpCertificate checks whether the user certificate of a jump host
+// connection is signed by the certificate authority of any namespace, since
+// the connection is not bound to a device yet.
+func evaluateJumpCertificate(cert *ssh.Certificate, user string) bool {
+	log := certificateLog(cert, user)
+
+	ca, err := client.NewClient().LookupCertificateAuthority(ssh.FingerprintSHA256(cert.SignatureKey))
+	if err != nil {
+		log.WithError(err).Warning("Rejected a certificate of an unknown authority")
+		authFailures.WithLabelValues(authFailureInvalidCertificate).Inc()
+
+		return false
+	}
+
+	return checkCertificate(cert, user, ca, log)
+}
+
+func certificateLog(cert *ssh.Certificate, user string) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"key_id": cert.KeyId,
+		"serial": cert.Serial,
+		"user":   user,
+	})
+}
+
+// checkCertificate checks the user certificate against the certificate
+// authority.
+func checkCertificate(cert *ssh.Certificate, user string, ca *models.CertificateAuthority, log *logrus.Entry) bool {
+	if cert.CertType != ssh.UserCert {
+		log.Warning("Rejected a host certificate")
+		authFailures.WithLabelValues(authFailureInvalidCertificate).Inc()
+
+		return false
+	}
+
+	caKey, _, _, _, err := ssh.ParseAuthorizedKey(ca.PublicKey) //nolint:dogsled
+	if err != nil {
+		log.WithError(err).Error("Failed to parse the certificate authority")
+		authFailures.WithLabelValues(authFailureInvalidCertificate).Inc()
+
+		return false
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), caKey.Marshal())
+		},
+		Clock: clock.Now,
+	}
+
+	// The signature, the validity period, the principals and the critical
+	// options, which are not supported, are checked.
+	if err := checker.CheckCert(user, cert); err != nil {
+		log.WithError(err).Warning("Rejected the certificate")
+		authFailures.WithLabelValues(authFailureInvalidCertificate).Inc()
+
+		return false
+	}
+
+	return true
+}
+
+// permits reports whether the certificate has the extension, which is always
+// the case of the connections authenticated by plain public keys or password.
+func permits(cert *ssh.Certificate, extension string) bool {
+	if cert == nil {
+		return true
+	}
+
+	_, ok := cert.Extensions[extension]
+
+	return ok
+}
+
+// certificatePermits reports whether the certificate authenticating the
+// connection, if any, has the extension.
+func certificatePermits(ctx sshserver.Context, extension string) bool {
+	cert, _ := ctx.Value(certificateContextKey).(*ssh.Certificate)
+
+	return permits(cert, extension)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/api/webhook"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/shellhub-io/shellhub/pkg/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrForwardingRejected = errors.New("connection rejected by webhook endpoint")
+
+const forwardingContextKey = "forwarding"
+
+// forwarding holds the connection to the device used to carry the port
+// forwardings requested by the user within a SSH connection.
+type forwarding struct {
+	session *Session
+	client  *ssh.Client
+}
+
+// direct-tcpip channel data as specified in RFC4254, Section 7.2
+type directTCPIPData struct {
+	DestAddr   string
+	DestPort   uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// tcpip-forward request payload as specified in RFC4254, Section 7.1
+type tcpipForwardRequest struct {
+	BindAddr string
+	BindPort uint32
+}
+
+func (s *Server) directTCPIPHandler(_ *sshserver.Server, _ *ssh.ServerConn, newChan ssh.NewChannel, ctx sshserver.Context) {
+	data := directTCPIPData{}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &data); err != nil {
+		newChan.Reject(ssh.ConnectionFailed, "error parsing forward data: "+err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	if key, ok := ctx.Value(jumpContextKey).(ssh.PublicKey); ok {
+		s.jumpHandler(newChan, ctx, key, &data)
+
+		return
+	}
+
+	if !certificatePermits(ctx, permitPortForwarding) {
+		newChan.Reject(ssh.Prohibited, "port forwarding is not permitted by the certificate") // nolint:errcheck
+
+		return
+	}
+
+	fwd, err := s.forwarding(ctx)
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	if err := fwd.log(models.SessionForwardLocal, data.DestAddr, data.DestPort); err != nil {
+		if errors.Is(err, client.ErrForbidden) {
+			newChan.Reject(ssh.Prohibited, "port forwarding is disabled") // nolint:errcheck
+		} else {
+			newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+		}
+
+		return
+	}
+
+	upstream, upstreamReqs, err := fwd.client.OpenChannel("direct-tcpip", newChan.ExtraData())
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	go ssh.DiscardRequests(upstreamReqs)
+
+	downstream, downstreamReqs, err := newChan.Accept()
+	if err != nil {
+		upstream.Close()
+
+		return
+	}
+
+	go ssh.DiscardRequests(downstreamReqs)
+
+	pipeChannels(downstream, upstream)
+}
+
+func (s *Server) tcpipForwardHandler(ctx sshserver.Context, _ *sshserver.Server, req *ssh.Request) (bool, []byte) {
+	payload := tcpipForwardRequest{}
+	if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+		return false, nil
+	}
+
+	var fwd *forwarding
+
+	if req.Type == "cancel-tcpip-forward" {
+		ctx.Lock()
+		fwd, _ = ctx.Value(forwardingContextKey).(*forwarding)
+		ctx.Unlock()
+
+		if fwd == nil {
+			return false, nil
+		}
+	} else {
+		if !certificatePermits(ctx, permitPortForwarding) {
+			return false, nil
+		}
+
+		var err error
+		if fwd, err = s.forwarding(ctx); err != nil {
+			return false, nil
+		}
+
+		if err := fwd.log(models.SessionForwardRemote, payload.BindAddr, payload.BindPort); err != nil {
+			return false, nil
+		}
+	}
+
+	ok, reply, err := fwd.client.SendRequest(req.Type, true, req.Payload)
+	if err != nil {
+		return false, nil
+	}
+
+	return ok, reply
+}
+
+// forwarding returns the connection to the device used for port forwarding,
+// establishing it on the first request made within the SSH connection.
+func (s *Server) forwarding(ctx sshserver.Context) (*forwarding, error) {
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	if fwd, ok := ctx.Value(forwardingContextKey).(*forwarding); ok {
+		return fwd, nil
+	}
+
+	fwd, err := s.newForwarding(ctx)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"err":     err,
+			"session": ctx.SessionID(),
+		}).Error("Failed to establish port forwarding connection")
+
+		return nil, err
+	}
+
+	ctx.SetValue(forwardingContextKey, fwd)
+
+	return fwd, nil
+}
+
+func (s *Server) newForwarding(ctx sshserver.Context) (*forwarding, error) {
+	sess, err := newSession(ctx.User(), ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// A connection may carry both a shell session and port forwardings, so
+	// the forwarding session needs its own identifier on the device.
+	sess.UID = uuid.Generate()
+
+	if wh := webhook.NewClient(); wh != nil {
+		res, err := wh.Connect(sess.Lookup)
+		if errors.Is(err, webhook.ErrForbidden) {
+			return nil, ErrForwardingRejected
+		}
+
+		if res != nil {
+			time.Sleep(time.Duration(res.Timeout) * time.Second)
+		}
+	}
+
+	passwd, privKey, err := credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := sess.clientConfig(passwd, privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.tunnel.Dial(context.Background(), sess.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sess.register(nil); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"target":   sess.Target,
+			"username": sess.User,
+			"session":  sess.UID,
+		}).Error("Failed to register session")
+	}
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/ssh/%s", sess.UID), nil)
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		s.closeSession(sess)
+
+		return nil, err
+	}
+
+	sshClient, err := NewClientConnWithDeadline(conn, "tcp", config)
+	if err != nil {
+		conn.Close()
+		s.closeSession(sess)
+
+		reportAuthFailure(&models.AuthAttempt{IPAddress: sess.IPAddress, Username: sess.User, Device: sess.Target})
+
+		return nil, err
+	}
+
+	if errs := client.NewClient().PatchSessions(sess.UID); len(errs) > 0 {
+		sshClient.Close()
+		s.closeSession(sess)
+
+		return nil, errs[0]
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"target":   sess.Target,
+		"username": sess.User,
+		"session":  sess.UID,
+	}).Info("Port forwarding connection established")
+
+	activeSessions.WithLabelValues("forwarding").Inc()
+
+	serverConn := ctx.Value(sshserver.ContextKeyConn).(*ssh.ServerConn)
+
+	go func() {
+		for newChan := range sshClient.HandleChannelOpen("forwarded-tcpip") {
+			go forwardChannel(serverConn, newChan)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		sshClient.Close()
+		s.closeSession(sess)
+
+		activeSessions.WithLabelValues("forwarding").Dec()
+	}()
+
+	return &forwarding{session: sess, client: sshClient}, nil
+}
+
+// log records the port forwarding in the session, which fails when port
+// forwarding is disabled in the namespace of the device.
+func (f *forwarding) log(kind, host string, port uint32) error {
+	err := client.NewClient().CreateSessionForward(f.session.UID, &models.SessionForward{
+		Type: kind,
+		Host: host,
+		Port: port,
+	})
+
+	logrus.WithFields(logrus.Fields{
+		"session": f.session.UID,
+		"type":    kind,
+		"host":    host,
+		"port":    port,
+		"allowed": err == nil,
+	}).Info("Port forwarding requested")
+
+	return err
+}
+
+// forwardChannel relays a channel opened by the device to the user.
+func forwardChannel(conn *ssh.ServerConn, newChan ssh.NewChannel) {
+	downstream, downstreamReqs, err := conn.OpenChannel(newChan.ChannelType(), newChan.ExtraData())
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	go ssh.DiscardRequests(downstreamReqs)
+
+	upstream, upstreamReqs, err := newChan.Accept()
+	if err != nil {
+		downstream.Close()
+
+		return
+	}
+
+	go ssh.DiscardRequests(upstreamReqs)
+
+	pipeChannels(downstream, upstream)
+}
+
+// pipeChannels copies data between both channels until each side is done.
+func pipeChannels(a, b ssh.Channel) {
+	var wg sync.WaitGroup
+
+	copyChannel := func(dst, src ssh.Channel) {
+		defer wg.Done()
+
+		io.Copy(dst, src) // nolint:errcheck
+		dst.CloseWrite()  // nolint:errcheck
+	}
+
+	wg.Add(2)
+
+	go copyChannel(a, b)
+	go copyChannel(b, a)
+
+	wg.Wait()
+
+	a.Close()
+	b.Close()
+}
@@ -10,10 +10,11 @@ require (
 	github.com/leodido/go-urn v1.2.1 // indirect
 	github.com/parnurzeal/gorequest v0.2.16
 	github.com/pires/go-proxyproto v0.6.0
+	github.com/prometheus/client_golang v1.11.1
 	github.com/shellhub-io/shellhub v0.7.1
 	github.com/sirupsen/logrus v1.8.1
-	golang.org/x/crypto v0.0.0-20210616213533-5ff15b29337e
-	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
+	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.25.0
 )
 
 replace github.com/shellhub-io/shellhub => ../
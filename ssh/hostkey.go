@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrHostKeyMismatch = errors.New("host key of the device does not match its public key")
+
+// deviceHostKeyCallback pins the host key of the device to the public key it
+// registered with the server, so a connection to the device can not be
+// impersonated through the tunnel.
+func deviceHostKeyCallback(device *models.Device) ssh.HostKeyCallback {
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		if device == nil {
+			return ErrHostKeyMismatch
+		}
+
+		pubKey, err := device.HostKey()
+		if err != nil {
+			return err
+		}
+
+		expected, err := ssh.NewPublicKey(pubKey)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(key.Marshal(), expected.Marshal()) {
+			logrus.WithFields(logrus.Fields{
+				"device":      device.UID,
+				"fingerprint": ssh.FingerprintSHA256(key),
+				"expected":    ssh.FingerprintSHA256(expected),
+			}).Error("Device host key mismatch")
+
+			authFailures.WithLabelValues(authFailureHostKeyMismatch).Inc()
+
+			return ErrHostKeyMismatch
+		}
+
+		return nil
+	}
+}
+
+// gatewayHostKey returns the host key of the SSH server of the gateway.
+func gatewayHostKey() (ssh.PublicKey, error) {
+	data, err := ioutil.ReadFile(os.Getenv("PRIVATE_KEY"))
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.PublicKey(), nil
+}
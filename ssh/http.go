@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/httptunnel"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotMember            = errors.New("user is not a member of the device namespace")
+	ErrInvalidDeviceHTTPURL = errors.New("invalid device HTTP address")
+	ErrInvalidHTTPToken     = errors.New("invalid or expired access token")
+)
+
+const (
+	// httpTokenParam is the query parameter of the link to a device port
+	// holding the token exchanged for the access cookie.
+	httpTokenParam = "shellhub-token"
+	// httpTokenCookie holds the token granting access to the device port
+	// served from the origin of the cookie.
+	httpTokenCookie = "shellhub-http-token"
+	// httpLinkTTL is how long the link to a device port can be opened for,
+	// and httpSessionTTL how long the device port can be browsed for.
+	httpLinkTTL    = time.Minute
+	httpSessionTTL = time.Hour
+)
+
+// deviceHTTPDomain returns the domain whose subdomains serve the HTTP ports of
+// the devices, each of them from its own origin apart from the one of the UI,
+// which keeps the credentials of the user. It is empty when the HTTP proxy is
+// disabled.
+func deviceHTTPDomain() string {
+	return os.Getenv("DEVICE_HTTP_DOMAIN")
+}
+
+// deviceHTTPEncoding encodes the device UID in a single DNS label, which is
+// too short for its hexadecimal form.
+var deviceHTTPEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// deviceHTTPHost returns the host serving the port of the device.
+func deviceHTTPHost(uid string, port int, domain string) (string, error) {
+	data, err := hex.DecodeString(uid)
+	if err != nil {
+		return "", ErrInvalidDeviceHTTPURL
+	}
+
+	return fmt.Sprintf("%d-%s.%s", port, deviceHTTPEncoding.EncodeToString(data), domain), nil
+}
+
+// parseDeviceHTTPHost returns the device UID and the port served by the host.
+func parseDeviceHTTPHost(host, domain string) (string, int, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(host)
+
+	label := strings.TrimSuffix(host, "."+strings.ToLower(domain))
+	if label == host || strings.Contains(label, ".") {
+		return "", 0, ErrInvalidDeviceHTTPURL
+	}
+
+	parts := strings.SplitN(label, "-", 2)
+	if len(parts) != 2 {
+		return "", 0, ErrInvalidDeviceHTTPURL
+	}
+
+	port, err := strconv.Atoi(parts[0])
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, ErrInvalidDeviceHTTPURL
+	}
+
+	data, err := deviceHTTPEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", 0, ErrInvalidDeviceHTTPURL
+	}
+
+	return hex.EncodeToString(data), port, nil
+}
+
+// isDeviceHTTPHost reports whether the request is made to the origin of a
+// device port.
+func isDeviceHTTPHost(req *http.Request) bool {
+	domain := deviceHTTPDomain()
+	if domain == "" {
+		return false
+	}
+
+	_, _, err := parseDeviceHTTPHost(req.Host, domain)
+
+	return err == nil
+}
+
+// httpToken grants access to a port of a device until it expires.
+type httpToken struct {
+	Device  string `json:"device"`
+	Port    int    `json:"port"`
+	Expires int64  `json:"exp"`
+}
+
+// newHTTPToken returns a token granting access to the port of the device for
+// the ttl.
+func newHTTPToken(uid string, port int, ttl time.Duration) (string, error) {
+	key, err := signingKey("http")
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(httpToken{Device: uid, Port: port, Expires: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload)) // nolint:errcheck
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// checkHTTPToken checks whether the token grants access to the port of the
+// device.
+func checkHTTPToken(token, uid string, port int) error {
+	key, err := signingKey("http")
+	if err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return ErrInvalidHTTPToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidHTTPToken
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0])) // nolint:errcheck
+
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return ErrInvalidHTTPToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidHTTPToken
+	}
+
+	var t httpToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return ErrInvalidHTTPToken
+	}
+
+	if t.Device != uid || t.Port != port || time.Now().Unix() > t.Expires {
+		return ErrInvalidHTTPToken
+	}
+
+	return nil
+}
+
+// isHTTPS reports whether the client made the request over HTTPS, which is
+// terminated before the gateway.
+func isHTTPS(req *http.Request) bool {
+	return req.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// httpLinkHandler returns the link to a port of a device to the members of its
+// namespace. The link holds a short-lived token, exchanged for a cookie scoped
+// to the origin of the device port when it is opened.
+func httpLinkHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	domain := deviceHTTPDomain()
+	if domain == "" {
+		http.Error(res, "HTTP proxy is disabled", http.StatusNotFound)
+
+		return
+	}
+
+	if err := authorizeHTTPProxy(vars["uid"], req.Header.Get("X-Tenant-ID"), req.Header.Get("X-ID")); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"uid":  vars["uid"],
+			"user": req.Header.Get("X-Username"),
+			"err":  err,
+		}).Warning("HTTP proxy to device rejected")
+
+		switch err {
+		case client.ErrConnectionFailed:
+			http.Error(res, err.Error(), http.StatusBadGateway)
+		case ErrNotMember:
+			http.Error(res, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(res, "device not found", http.StatusNotFound)
+		}
+
+		return
+	}
+
+	port, _ := strconv.Atoi(vars["port"])
+
+	host, err := deviceHTTPHost(vars["uid"], port, domain)
+	if err != nil {
+		http.Error(res, "device not found", http.StatusNotFound)
+
+		return
+	}
+
+	// The device ports are served on the same port as the UI.
+	if _, p, err := net.SplitHostPort(req.Host); err == nil {
+		host = net.JoinHostPort(host, p)
+	}
+
+	token, err := newHTTPToken(vars["uid"], port, httpLinkTTL)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to issue the HTTP proxy token")
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	link := url.URL{Scheme: "http", Host: host, Path: "/", RawQuery: url.Values{httpTokenParam: {token}}.Encode()}
+	if isHTTPS(req) {
+		link.Scheme = "https"
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(map[string]string{"url": link.String()}) // nolint:errcheck
+}
+
+// httpProxyHandler proxies the HTTP requests, websockets included, made to the
+// origin of a port of a device, as long as they carry a token granting access
+// to it. The agent decides which ports of the device can be reached.
+func httpProxyHandler(tunnel *httptunnel.Tunnel) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		uid, port, err := parseDeviceHTTPHost(req.Host, deviceHTTPDomain())
+		if err != nil {
+			http.Error(res, "device not found", http.StatusNotFound)
+
+			return
+		}
+
+		// The token of the link is exchanged for a cookie, so the following
+		// requests, websockets included, are authorized as the browser makes
+		// them.
+		if token := req.URL.Query().Get(httpTokenParam); token != "" {
+			if err := checkHTTPToken(token, uid, port); err != nil {
+				http.Error(res, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			session, err := newHTTPToken(uid, port, httpSessionTTL)
+			if err != nil {
+				http.Error(res, err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+
+			http.SetCookie(res, &http.Cookie{
+				Name:     httpTokenCookie,
+				Value:    session,
+				Path:     "/",
+				MaxAge:   int(httpSessionTTL.Seconds()),
+				HttpOnly: true,
+				Secure:   isHTTPS(req),
+				SameSite: http.SameSiteLaxMode,
+			})
+
+			query := req.URL.Query()
+			query.Del(httpTokenParam)
+
+			// The location is kept on the origin, as a path starting with
+			// two slashes is a reference to another host.
+			location := "/" + strings.TrimLeft(req.URL.EscapedPath(), "/")
+			if len(query) > 0 {
+				location += "?" + query.Encode()
+			}
+
+			http.Redirect(res, req, location, http.StatusFound)
+
+			return
+		}
+
+		cookie, err := req.Cookie(httpTokenCookie)
+		if err != nil || checkHTTPToken(cookie.Value, uid, port) != nil {
+			http.Error(res, ErrInvalidHTTPToken.Error(), http.StatusForbidden)
+
+			return
+		}
+
+		proxy := &httputil.ReverseProxy{
+			Director: func(req *http.Request) {
+				// The agent serves the ports of the device at /http/{port}
+				prefix := fmt.Sprintf("/http/%d", port)
+
+				req.URL.Scheme = "http"
+				req.URL.Host = uid
+				req.URL.Path = prefix + req.URL.Path
+				if req.URL.RawPath != "" {
+					req.URL.RawPath = prefix + req.URL.RawPath
+				}
+
+				// The credentials of the user are not meant for the device
+				req.Header.Del("Authorization")
+				req.Header.Del("X-Tenant-ID")
+				req.Header.Del("X-Username")
+				req.Header.Del("X-ID")
+				stripCookie(req, httpTokenCookie)
+			},
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return tunnel.Dial(ctx, uid)
+				},
+				DisableKeepAlives: true,
+			},
+			ErrorHandler: func(res http.ResponseWriter, _ *http.Request, err error) {
+				http.Error(res, err.Error(), http.StatusServiceUnavailable)
+			},
+		}
+
+		proxy.ServeHTTP(res, req)
+	}
+}
+
+// stripCookie removes the cookie from the request, keeping the other ones,
+// which were set by the device on its own origin.
+func stripCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+
+	for _, c := range cookies {
+		if c.Name != name {
+			req.AddCookie(c)
+		}
+	}
+}
+
+// authorizeHTTPProxy checks if the device belongs to the tenant and the user
+// is a member of its namespace.
+func authorizeHTTPProxy(uid, tenant, id string) error {
+	cli := client.NewClient()
+
+	device, err := cli.GetDevice(uid)
+	if err != nil {
+		return err
+	}
+
+	if tenant == "" || device.TenantID != tenant {
+		return client.ErrNotFound
+	}
+
+	namespace, err := cli.GetNamespace(tenant)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range namespace.Members {
+		if isMember(member, id) {
+			return nil
+		}
+	}
+
+	return ErrNotMember
+}
+
+// isMember reports whether the member, as decoded from the namespace
+// returned by the API, has the given user id.
+func isMember(member interface{}, id string) bool {
+	switch m := member.(type) {
+	case string:
+		return m == id
+	case map[string]interface{}:
+		return m["id"] == id
+	}
+
+	return false
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/api/webhook"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/shellhub-io/shellhub/pkg/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// jumpContextKey holds the public key, or the user certificate, authenticating
+// a jump host connection (e.g. ssh -J user@gateway user@namespace.device).
+const jumpContextKey = "jump"
+
+// jumpPort is the only port of the devices reachable through the gateway used
+// as a jump host, where the SSH server of the agent is served.
+const jumpPort = 22
+
+// isJumpUser reports whether the login user is of a jump host connection,
+// which is not addressed to a device like the session targets.
+func isJumpUser(user string) bool {
+	return !strings.Contains(user, "@")
+}
+
+// jumpHandler splices a direct-tcpip channel requested through a jump host
+// connection to the SSH server of the device, so the session is end-to-end
+// encrypted between the client and the agent. The destination is the device
+// address (namespace.device) or its UID, and the login user of the jump host
+// is the device user the firewall rules are evaluated for. The gateway can not
+// see into the session, so the agent is told what the session is restricted
+// from.
+func (s *Server) jumpHandler(newChan ssh.NewChannel, ctx sshserver.Context, key ssh.PublicKey, data *directTCPIPData) {
+	if data.DestPort != jumpPort {
+		newChan.Reject(ssh.Prohibited, fmt.Sprintf("only port %d of the devices is reachable", jumpPort)) // nolint:errcheck
+
+		return
+	}
+
+	sess, err := newSession(fmt.Sprintf("%s@%s", ctx.User(), data.DestAddr), ctx, nil)
+	if err != nil {
+		authFailures.WithLabelValues(authFailureDeviceNotFound).Inc()
+
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	if !authorizeKey(key, ctx.User(), sess.device) {
+		newChan.Reject(ssh.Prohibited, "public key not allowed") // nolint:errcheck
+
+		return
+	}
+
+	// The jump host connections are port forwardings, as OpenSSH sees them.
+	if cert, ok := key.(*ssh.Certificate); ok && !permits(cert, permitPortForwarding) {
+		newChan.Reject(ssh.Prohibited, "port forwarding is not permitted by the certificate") // nolint:errcheck
+
+		return
+	}
+
+	if !jumpMFA(ctx, sess.device) {
+		newChan.Reject(ssh.Prohibited, "verification code required by the namespace") // nolint:errcheck
+
+		return
+	}
+
+	// A jump host connection may carry channels to several devices, so
+	// each of them needs its own identifier on the device.
+	sess.connID = uuid.Generate()
+
+	log := logrus.WithFields(logrus.Fields{
+		"target":    sess.Target,
+		"container": sess.Container,
+		"username":  sess.User,
+		"session":   ctx.SessionID(),
+	})
+
+	if wh := webhook.NewClient(); wh != nil {
+		res, err := wh.Connect(sess.Lookup)
+		if errors.Is(err, webhook.ErrForbidden) {
+			newChan.Reject(ssh.Prohibited, "connection rejected by webhook endpoint") // nolint:errcheck
+
+			return
+		}
+
+		if res != nil {
+			time.Sleep(time.Duration(res.Timeout) * time.Second)
+		}
+	}
+
+	restrictions, err := jumpRestrictions(sess, key)
+	if err != nil {
+		log.WithError(err).Error("Failed to get the namespace of the device")
+
+		newChan.Reject(ssh.ConnectionFailed, "failed to get the namespace of the device") // nolint:errcheck
+
+		return
+	}
+
+	conn, err := s.tunnel.Dial(context.Background(), sess.Target)
+	if err != nil {
+		log.WithError(err).Error("Failed to dial to tunnel")
+
+		newChan.Reject(ssh.ConnectionFailed, "device is not reachable") // nolint:errcheck
+
+		return
+	}
+	defer conn.Close()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/ssh/%s", sess.connID), nil)
+
+	query := url.Values{}
+
+	// The agent serves the SSH server inside the container instead of the
+	// device
+	if sess.Container != "" {
+		query.Set("container", sess.Container)
+	}
+
+	if len(restrictions) > 0 {
+		query.Set("restrict", strings.Join(restrictions, ","))
+	}
+
+	req.URL.RawQuery = query.Encode()
+
+	if err = req.Write(conn); err != nil {
+		log.WithError(err).Error("Failed to write")
+
+		newChan.Reject(ssh.ConnectionFailed, err.Error()) // nolint:errcheck
+
+		return
+	}
+
+	channel, reqs, err := newChan.Accept()
+	if err != nil {
+		s.closeConn(sess) // nolint:errcheck
+
+		return
+	}
+
+	go ssh.DiscardRequests(reqs)
+
+	log.Info("Jump host connection established")
+
+	activeSessions.WithLabelValues("jump").Inc()
+	defer activeSessions.WithLabelValues("jump").Dec()
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		io.Copy(conn, channel) // nolint:errcheck
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(channel, conn) // nolint:errcheck
+		done <- struct{}{}
+	}()
+
+	// The SSH protocol does not half close the connection, so either side
+	// finishing ends the session.
+	<-done
+
+	channel.Close()
+	s.closeConn(sess) // nolint:errcheck
+
+	log.Info("Jump host connection closed")
+}
+
+// jumpRestrictions returns the restrictions of the session spliced to the
+// device, which are the features disabled in the namespace of the device or
+// not permitted by the certificate of the user, as the gateway enforces them
+// on the sessions it terminates.
+func jumpRestrictions(sess *Session, key ssh.PublicKey) ([]string, error) {
+	namespace, err := client.NewClient().GetNamespace(sess.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, _ := key.(*ssh.Certificate)
+
+	var restrictions []string
+
+	if !permits(cert, permitPty) {
+		restrictions = append(restrictions, models.SessionRestrictPty)
+	}
+
+	if namespace.Settings == nil || !namespace.Settings.PortForwarding || !permits(cert, permitPortForwarding) {
+		restrictions = append(restrictions, models.SessionRestrictPortForwarding)
+	}
+
+	if namespace.Settings == nil || !namespace.Settings.AgentForwarding || !permits(cert, permitAgentForwarding) {
+		restrictions = append(restrictions, models.SessionRestrictAgentForwarding)
+	}
+
+	return restrictions, nil
+}
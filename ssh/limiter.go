@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// authLimitContextKey holds the login user (user@device) whose authentication
+// limit was applied to the connection, since the authentication handlers run
+// for each key offered by the client.
+const authLimitContextKey = "auth_limit"
+
+// webClients maps the local address of the connections made by the web
+// terminal to the gateway itself to the IP address of their web client.
+var webClients sync.Map
+
+// registerWebClient tells the IP address of the web client of the connection
+// to the gateway made from addr, until the returned function is called.
+func registerWebClient(addr net.Addr, ip string) func() {
+	webClients.Store(addr.String(), ip)
+
+	return func() {
+		webClients.Delete(addr.String())
+	}
+}
+
+// remoteIP returns the IP address of the client. The connections made from the
+// gateway itself are the web terminal ones, whose client is registered by the
+// websocket handler, or unknown otherwise.
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+
+	if ip := net.ParseIP(host); ip == nil || ip.IsLoopback() {
+		if ip, ok := webClients.Load(addr.String()); ok {
+			return ip.(string)
+		}
+
+		return ""
+	}
+
+	return host
+}
+
+// authAttempt returns the authentication attempt of the connection to the
+// device.
+func authAttempt(ctx sshserver.Context, device *models.Device) *models.AuthAttempt {
+	return &models.AuthAttempt{
+		IPAddress: remoteIP(ctx.RemoteAddr()),
+		Username:  strings.SplitN(ctx.User(), "@", 2)[0],
+		Device:    device.UID,
+	}
+}
+
+// checkAuthLimit delays the authentication of the clients, users and devices
+// failing repeatedly, and rejects the banned ones. The limits are not applied
+// when the API can not tell them.
+func checkAuthLimit(ctx sshserver.Context, attempt *models.AuthAttempt) bool {
+	login := attempt.Username + "@" + attempt.Device
+	if checked, ok := ctx.Value(authLimitContextKey).(string); ok && checked == login {
+		return true
+	}
+
+	limit, err := client.NewClient().CheckAuthLimit(attempt)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check the authentication limit")
+
+		return true
+	}
+
+	if limit.BannedUntil != nil {
+		logrus.WithFields(logrus.Fields{
+			"ip_address": attempt.IPAddress,
+			"username":   attempt.Username,
+			"device":     attempt.Device,
+			"until":      limit.BannedUntil,
+		}).Warning("Rejected a banned authentication attempt")
+
+		authFailures.WithLabelValues(authFailureBanned).Inc()
+
+		return false
+	}
+
+	ctx.SetValue(authLimitContextKey, login)
+
+	delayAuth(limit)
+
+	return true
+}
+
+// reportAuthFailure counts the failure of the authentication attempt, delaying
+// the reply to the client as the next attempts are.
+func reportAuthFailure(attempt *models.AuthAttempt) {
+	limit, err := client.NewClient().ReportAuthFailure(attempt)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to report the authentication failure")
+
+		return
+	}
+
+	delayAuth(limit)
+}
+
+func delayAuth(limit *models.AuthLimit) {
+	if limit.Delay > 0 {
+		time.Sleep(time.Duration(limit.Delay) * time.Second)
+	}
+}
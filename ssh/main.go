@@ -1,15 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
-	"crypto/rsa"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/net/websocket"
 
 	"github.com/shellhub-io/shellhub/pkg/api/client"
@@ -17,7 +21,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var magicKey *rsa.PrivateKey
+// magicKey is used by the web terminal to authenticate on the SSH server on
+// behalf of the users authenticated with their public keys.
+var magicKey ssh.Signer
 
 type Options struct {
 	Addr           string
@@ -31,6 +37,8 @@ func main() {
 		return r.Header.Get(client.DeviceUIDHeader), nil
 	}
 
+	instrumentTunnel(tunnel)
+
 	router := tunnel.Router().(*mux.Router)
 	router.HandleFunc("/sessions/{uid}/close", func(res http.ResponseWriter, req *http.Request) {
 		vars := mux.Vars(req)
@@ -59,9 +67,65 @@ func main() {
 			return
 		}
 	})
+	router.HandleFunc("/devices/{uid}/exec", func(res http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		// The command of the job is executed by the agent, which replies
+		// with its output once it finishes.
+		execReq, _ := http.NewRequest("POST", "/exec", bytes.NewReader(body))
+		execReq.Header.Set("Content-Type", "application/json")
+		execReq.Close = true
+
+		resp, err := tunnel.SendRequest(req.Context(), vars["uid"], execReq)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		tunnel.ForwardResponse(resp, res)
+	}).Methods("POST")
+	router.HandleFunc("/devices/{uid}/diagnostics", func(res http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+
+		// The agent replies with the diagnostics bundle as a tar file.
+		diagReq, _ := http.NewRequest("POST", "/diagnostics", nil)
+		diagReq.Close = true
+
+		resp, err := tunnel.SendRequest(req.Context(), vars["uid"], diagReq)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		tunnel.ForwardResponse(resp, res)
+	}).Methods("POST")
+	router.HandleFunc("/api/devices/{uid}/http/{port:[0-9]+}", httpLinkHandler).Methods("GET")
 	router.Handle("/ws/ssh", websocket.Handler(HandlerWebsocket))
+	router.Handle("/metrics", promhttp.Handler())
+
+	// The origins of the device ports are served apart from the routes of
+	// the gateway, which the devices must not reach.
+	proxy := httpProxyHandler(tunnel)
+	handler := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if isDeviceHTTPHost(req) {
+			proxy(res, req)
 
-	go http.ListenAndServe(":8080", router) // nolint:errcheck
+			return
+		}
+
+		router.ServeHTTP(res, req)
+	})
+
+	go http.ListenAndServe(":8080", handler) // nolint:errcheck
 
 	go func() {
 		for {
@@ -73,12 +137,15 @@ func main() {
 		}
 	}()
 
-	var err error
-	magicKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
 		logrus.Fatal(err)
 	}
 
+	if magicKey, err = ssh.NewSignerFromKey(key); err != nil {
+		logrus.Fatal(err)
+	}
+
 	logrus.Fatal(NewServer(&Options{
 		Addr:           ":2222",
 		Broker:         "tcp://emq:1883",
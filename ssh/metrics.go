@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/shellhub-io/shellhub/pkg/connman"
+	"github.com/shellhub-io/shellhub/pkg/httptunnel"
+)
+
+var (
+	activeSessions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "shellhub",
+		Subsystem: "ssh",
+		Name:      "active_sessions",
+		Help:      "Number of sessions in progress by type (shell, forwarding or jump).",
+	}, []string{"type"})
+
+	dialDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "shellhub",
+		Subsystem: "ssh",
+		Name:      "device_dial_duration_seconds",
+		Help:      "Latency of the dials to the devices through the tunnel.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	dialFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "shellhub",
+		Subsystem: "ssh",
+		Name:      "device_dial_failures_total",
+		Help:      "Number of failed dials to the devices by reason.",
+	}, []string{"reason"})
+
+	authFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "shellhub",
+		Subsystem: "ssh",
+		Name:      "auth_failures_total",
+		Help:      "Number of failed authentications by reason.",
+	}, []string{"reason"})
+)
+
+// Reasons of the authentication failures.
+const (
+	authFailureInvalidTarget      = "invalid_target"
+	authFailureDeviceNotFound     = "device_not_found"
+	authFailureUnknownKey         = "unknown_key"
+	authFailureKeyNotAllowed      = "key_not_allowed"
+	authFailureDeviceRejected     = "device_rejected"
+	authFailureHostKeyMismatch    = "host_key_mismatch"
+	authFailureMFA                = "mfa_failed"
+	authFailureInvalidCertificate = "invalid_certificate"
+	authFailureBanned             = "banned"
+)
+
+// instrumentTunnel exports the devices connected to the tunnel and observes
+// its dials.
+func instrumentTunnel(tunnel *httptunnel.Tunnel) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "shellhub",
+		Subsystem: "ssh",
+		Name:      "connected_devices",
+		Help:      "Number of devices connected to the tunnel.",
+	}, func() float64 {
+		return float64(tunnel.Connections())
+	})
+
+	tunnel.DialObserver = func(_ string, elapsed time.Duration, err error) {
+		switch {
+		case err == nil:
+			dialDuration.Observe(elapsed.Seconds())
+		case errors.Is(err, connman.ErrNoConnection):
+			dialFailures.WithLabelValues("not_connected").Inc()
+		default:
+			dialFailures.WithLabelValues("error").Inc()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	sshserver "github.com/gliderlabs/ssh"
+	"github.com/shellhub-io/shellhub/pkg/api/client"
+	"github.com/shellhub-io/shellhub/pkg/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// mfaContextKey holds the tenant of the namespace the second factor of the
+// connection was verified for.
+const mfaContextKey = "mfa"
+
+// mfaCredentialsContextKey holds the second factor given by a jump host
+// connection, which is verified once against the namespace of each device
+// reached through it.
+const mfaCredentialsContextKey = "mfa_credentials"
+
+const (
+	mfaName        = "ShellHub"
+	mfaInstruction = "The namespace requires a verification code of your authenticator app."
+)
+
+var mfaQuestions = []string{"ShellHub username: ", "Verification code: "}
+
+type mfaCredentials struct {
+	username string
+	code     string
+
+	// verified holds the tenants of the namespaces the credentials were
+	// verified for, since the channels of a jump host connection are
+	// opened concurrently and the code is only accepted once.
+	mu       sync.Mutex
+	verified map[string]bool
+}
+
+// keyboardInteractiveHandler prompts the second factor of the logins to the
+// devices of the namespaces requiring MFA. The handler never authenticates the
+// connection by itself, since the older SSH protocol implementations do not
+// support partial success, so the client falls back to the password or the
+// public key authentication once the code is verified. The public key users
+// must therefore prefer the keyboard-interactive authentication (e.g. ssh -o
+// PreferredAuthentications=keyboard-interactive,publickey).
+func (s *Server) keyboardInteractiveHandler(ctx sshserver.Context, challenger ssh.KeyboardInteractiveChallenge) bool {
+	// The namespace of a jump host connection is only known by its
+	// channels, so the second factor is verified when they are opened.
+	if isJumpUser(ctx.User()) {
+		credentials, ok := mfaChallenge(challenger)
+		if ok {
+			ctx.SetValue(mfaCredentialsContextKey, credentials)
+		}
+
+		return false
+	}
+
+	device, err := lookupDevice(ctx.User())
+	if err != nil || !mfaPending(ctx, device) {
+		return false
+	}
+
+	credentials, ok := mfaChallenge(challenger)
+	if !ok {
+		return false
+	}
+
+	if verifyMFA(ctx, device, credentials) {
+		ctx.SetValue(mfaContextKey, device.TenantID)
+	}
+
+	return false
+}
+
+// mfaChallenge prompts the ShellHub username and the verification code.
+func mfaChallenge(challenger ssh.KeyboardInteractiveChallenge) (*mfaCredentials, bool) {
+	answers, err := challenger(mfaName, mfaInstruction, mfaQuestions, []bool{true, true})
+	if err != nil || len(answers) != len(mfaQuestions) {
+		return nil, false
+	}
+
+	return &mfaCredentials{
+		username: strings.TrimSpace(answers[0]),
+		code:     strings.TrimSpace(answers[1]),
+		verified: make(map[string]bool),
+	}, true
+}
+
+// verifyMFA verifies the second factor against the namespace of the device.
+func verifyMFA(ctx sshserver.Context, device *models.Device, credentials *mfaCredentials) bool {
+	if err := client.NewClient().AuthMFA(device.TenantID, credentials.username, credentials.code); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"username": credentials.username,
+			"tenant":   device.TenantID,
+			"session":  ctx.SessionID(),
+			"err":      err,
+		}).Warning("Failed to verify the second factor")
+
+		authFailures.WithLabelValues(authFailureMFA).Inc()
+
+		reportAuthFailure(authAttempt(ctx, device))
+
+		return false
+	}
+
+	return true
+}
+
+// mfaPending reports whether the namespace of the device requires MFA, which
+// was not verified yet by the connection.
+func mfaPending(ctx sshserver.Context, device *models.Device) bool {
+	if tenant, ok := ctx.Value(mfaContextKey).(string); ok && tenant == device.TenantID {
+		return false
+	}
+
+	namespace, err := client.NewClient().GetNamespace(device.TenantID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"tenant": device.TenantID,
+			"err":    err,
+		}).Error("Failed to get the namespace")
+
+		// The second factor is assumed required when it can not be told.
+		return true
+	}
+
+	return namespace.Settings != nil && namespace.Settings.MFA
+}
+
+// jumpMFA verifies the second factor of a jump host connection for the device
+// of a channel, which is only needed by the namespaces requiring MFA. Once
+// verified for a namespace, the later channels to its devices are accepted
+// without verifying the code again.
+func jumpMFA(ctx sshserver.Context, device *models.Device) bool {
+	credentials, ok := ctx.Value(mfaCredentialsContextKey).(*mfaCredentials)
+	if ok {
+		credentials.mu.Lock()
+		defer credentials.mu.Unlock()
+
+		if credentials.verified[device.TenantID] {
+			return true
+		}
+	}
+
+	if !mfaPending(ctx, device) {
+		return true
+	}
+
+	if !ok || !verifyMFA(ctx, device, credentials) {
+		return false
+	}
+
+	credentials.verified[device.TenantID] = true
+
+	return true
+}
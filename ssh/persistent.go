@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// persistEnv is set by the user to request a persistent session (e.g.
+	// ssh -o SetEnv=SHELLHUB_PERSIST=1 user@namespace.device).
+	persistEnv = "SHELLHUB_PERSIST"
+	// sessionEnv is set by the user to reattach to a persistent session,
+	// holding the token printed when it was started.
+	sessionEnv = "SHELLHUB_SESSION"
+	// agentPersistentSessionEnv is sent to the agent to keep the pty of the
+	// session alive when the user disconnects.
+	agentPersistentSessionEnv = "SHELLHUB_PERSISTENT_SESSION"
+)
+
+var ErrInvalidSessionToken = errors.New("invalid session token")
+
+// sessionToken returns the token used to reattach to the persistent session
+// uid, which is bound to the device and the user of the session. No token is
+// issued without the key to sign it.
+func sessionToken(uid, device, user string) (string, error) {
+	key, err := signingKey("session")
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{uid, device, user}, "|"))) // nolint:errcheck
+
+	return uid + "." + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseSessionToken returns the session uid of the token, as long as it was
+// issued for the device and the user.
+func parseSessionToken(token, device, user string) (string, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", ErrInvalidSessionToken
+	}
+
+	expected, err := sessionToken(parts[0], device, user)
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(token)) {
+		return "", ErrInvalidSessionToken
+	}
+
+	return parts[0], nil
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -19,10 +20,16 @@ import (
 	"github.com/shellhub-io/shellhub/pkg/api/client"
 	"github.com/shellhub-io/shellhub/pkg/api/webhook"
 	"github.com/shellhub-io/shellhub/pkg/httptunnel"
+	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
 )
 
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrDeviceNotFound     = errors.New("device not found")
+)
+
 type Server struct {
 	sshd   *sshserver.Server
 	opts   *Options
@@ -36,10 +43,22 @@ func NewServer(opts *Options, tunnel *httptunnel.Tunnel) *Server {
 	}
 
 	s.sshd = &sshserver.Server{
-		Addr:             opts.Addr,
-		PasswordHandler:  s.passwordHandler,
-		PublicKeyHandler: s.publicKeyHandler,
-		Handler:          s.sessionHandler,
+		Addr:                       opts.Addr,
+		PasswordHandler:            s.passwordHandler,
+		PublicKeyHandler:           s.publicKeyHandler,
+		KeyboardInteractiveHandler: s.keyboardInteractiveHandler,
+		Handler:                    s.sessionHandler,
+		ChannelHandlers: map[string]sshserver.ChannelHandler{
+			"session":      sshserver.DefaultSessionHandler,
+			"direct-tcpip": s.directTCPIPHandler,
+		},
+		RequestHandlers: map[string]sshserver.RequestHandler{
+			"tcpip-forward":        s.tcpipForwardHandler,
+			"cancel-tcpip-forward": s.tcpipForwardHandler,
+		},
+		SubsystemHandlers: map[string]sshserver.SubsystemHandler{
+			"sftp": s.sessionHandler,
+		},
 	}
 
 	if _, err := os.Stat(os.Getenv("PRIVATE_KEY")); os.IsNotExist(err) {
@@ -104,13 +123,28 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 		return
 	}
 
+	activeSessions.WithLabelValues("shell").Inc()
+	defer activeSessions.WithLabelValues("shell").Dec()
+
 	logrus.WithFields(logrus.Fields{
-		"target":   sess.Target,
-		"username": sess.User,
-		"session":  session.Context().Value(sshserver.ContextKeySessionID),
+		"target":    sess.Target,
+		"container": sess.Container,
+		"username":  sess.User,
+		"session":   session.Context().Value(sshserver.ContextKeySessionID),
 	}).Info("Session created")
 
-	if err = sess.register(session); err != nil {
+	if sess.Persistent {
+		sess.State = models.SessionStateAttached
+	}
+
+	if sess.reattach {
+		if err = client.NewClient().SetSessionState(sess.UID, models.SessionStateAttached); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":     err,
+				"session": sess.UID,
+			}).Error("Failed to set the session state")
+		}
+	} else if err = sess.register(session); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"target":   sess.Target,
 			"username": sess.User,
@@ -118,40 +152,28 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 		}).Error("Failed to register session")
 	}
 
-	var privKey *rsa.PrivateKey
-
-	publicKey, ok := session.Context().Value("public_key").(string)
-	if publicKey != "" && ok {
-		apiClient := client.NewClient()
-		key, err := apiClient.CreatePrivateKey()
-		if err != nil {
-			session.Close()
-
-			return
-		}
-
-		block, _ := pem.Decode(key.Data)
-
-		privKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			session.Close()
-
-			return
-		}
+	if sess.Persistent && !sess.reattach {
+		session.Write([]byte(fmt.Sprintf("Persistent session started, reattach to it with: ssh -o SetEnv=%s=%s\n", sessionEnv, sess.token))) // nolint:errcheck
 	}
 
-	passwd, ok := session.Context().Value("password").(string)
-	if !ok && privKey == nil {
+	passwd, privKey, err := credentials(session.Context().(sshserver.Context))
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
+			"err":     err,
 			"session": session.Context().Value(sshserver.ContextKeySessionID),
-		}).Error("Failed to get password from context")
+		}).Error("Failed to get credentials from context")
 
 		session.Close()
 
 		return
 	}
 
-	req, _ := http.NewRequest("GET", fmt.Sprintf("/ssh/%s", sess.UID), nil)
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/ssh/%s", sess.connID), nil)
+
+	// The agent opens the shell inside the container instead of the device
+	if sess.Container != "" {
+		req.URL.RawQuery = url.Values{"container": {sess.Container}}.Encode()
+	}
 
 	if err = req.Write(conn); err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -170,45 +192,171 @@ func (s *Server) sessionHandler(session sshserver.Session) {
 			"session": session.Context().Value(sshserver.ContextKeySessionID),
 		}).Error("Failed to connect")
 
+		authFailures.WithLabelValues(authFailureDeviceRejected).Inc()
+
+		if errors.Is(err, ErrDeviceRejected) {
+			reportAuthFailure(&models.AuthAttempt{IPAddress: sess.IPAddress, Username: sess.User, Device: sess.Target})
+		}
+
 		session.Write([]byte("Permission denied\n")) // nolint:errcheck
 		session.Close()
 
 		return
 	}
 
-	conn, err = s.tunnel.Dial(context.Background(), sess.Target)
-	if err != nil {
+	s.closeSession(sess)
+}
+
+// closeSession closes the session on the device and marks it as finished,
+// unless it is a persistent session which is detached or attached by another
+// connection.
+func (s *Server) closeSession(sess *Session) {
+	if err := s.closeConn(sess); err != nil {
 		return
 	}
 
-	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/ssh/close/%s", sess.UID), nil)
+	switch {
+	case sess.replaced:
+	case sess.detached:
+		if err := client.NewClient().SetSessionState(sess.UID, models.SessionStateDetached); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"err":     err,
+				"session": sess.UID,
+			}).Error("Failed to set the session state")
+		}
+	default:
+		sess.finish() // nolint:errcheck
+	}
+}
+
+// closeConn closes the connection of the session on the device.
+func (s *Server) closeConn(sess *Session) error {
+	conn, err := s.tunnel.Dial(context.Background(), sess.Target)
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/ssh/close/%s", sess.connID), nil)
 	if err = req.Write(conn); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"err":     err,
-			"session": session.Context().Value(sshserver.ContextKeySessionID),
+			"session": sess.UID,
 		}).Error("Failed to write")
 	}
 
-	sess.finish() // nolint:errcheck
+	return nil
+}
+
+// credentials returns the password or the private key used to authenticate
+// on the device on behalf of the user.
+func credentials(ctx sshserver.Context) (string, *rsa.PrivateKey, error) {
+	var privKey *rsa.PrivateKey
+
+	publicKey, ok := ctx.Value("public_key").(string)
+	if publicKey != "" && ok {
+		apiClient := client.NewClient()
+		key, err := apiClient.CreatePrivateKey()
+		if err != nil {
+			return "", nil, err
+		}
+
+		block, _ := pem.Decode(key.Data)
+
+		privKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	passwd, ok := ctx.Value("password").(string)
+	if !ok && privKey == nil {
+		return "", nil, ErrMissingCredentials
+	}
+
+	return passwd, privKey, nil
 }
 
 func (s *Server) publicKeyHandler(ctx sshserver.Context, pubKey sshserver.PublicKey) bool {
-	fingerprint := ssh.FingerprintLegacyMD5(pubKey)
+	fingerprint := ssh.FingerprintSHA256(pubKey)
 	target := ctx.Value(sshserver.ContextKeyUser).(string)
 
-	parts := strings.SplitN(target, "@", 2)
-	if len(parts) != 2 {
+	// The key of a jump host connection is evaluated against the device
+	// of each channel, since the connection is not bound to a device. The
+	// last key accepted is the one the client signed for, since the SSH
+	// library only caches the result of the last key queried.
+	if isJumpUser(target) {
+		if !checkAuthLimit(ctx, &models.AuthAttempt{IPAddress: remoteIP(ctx.RemoteAddr()), Username: target}) {
+			return false
+		}
+
+		if !authorizeJumpKey(pubKey, target) {
+			return false
+		}
+
+		ctx.SetValue(jumpContextKey, pubKey)
+
+		return true
+	}
+
+	device, err := lookupDevice(target)
+	if err != nil {
+		if err == ErrInvalidSessionTarget {
+			authFailures.WithLabelValues(authFailureInvalidTarget).Inc()
+		} else {
+			authFailures.WithLabelValues(authFailureDeviceNotFound).Inc()
+		}
+
+		return false
+	}
+
+	if !checkAuthLimit(ctx, authAttempt(ctx, device)) {
+		return false
+	}
+
+	login := strings.SplitN(target, "@", 2)[0]
+
+	if ssh.FingerprintSHA256(magicKey.PublicKey()) != fingerprint && !authorizeKey(pubKey, login, device) {
 		return false
 	}
 
+	// The public key is only the first factor of the namespaces requiring
+	// MFA, which is verified by the keyboard-interactive authentication.
+	if mfaPending(ctx, device) {
+		return false
+	}
+
+	// The SSH library calls the handler last with the key the client signed
+	// for, so the last certificate accepted is the authenticating one. A
+	// plain key queried afterwards never lifts its restrictions.
+	if cert, ok := pubKey.(*ssh.Certificate); ok {
+		ctx.SetValue(certificateContextKey, cert)
+	}
+
+	ctx.SetValue("public_key", fingerprint)
+
+	return true
+}
+
+// lookupDevice resolves the device of the login user (user@device), which is
+// addressed by its UID or its namespace qualified name.
+func lookupDevice(user string) (*models.Device, error) {
+	parts := strings.SplitN(user, "@", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidSessionTarget
+	}
+
+	target, _, err := splitContainer(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
 	c := client.NewClient()
 
-	target = parts[1]
 	var lookup map[string]string
-	if !strings.Contains(parts[1], ".") {
+	if !strings.Contains(target, ".") {
 		device, err := c.GetDevice(target)
 		if err != nil {
-			return false
+			return nil, ErrDeviceNotFound
 		}
 
 		lookup = map[string]string{
@@ -216,9 +364,9 @@ func (s *Server) publicKeyHandler(ctx sshserver.Context, pubKey sshserver.Public
 			"name":   device.Name,
 		}
 	} else {
-		parts = strings.SplitN(parts[1], ".", 2)
+		parts = strings.SplitN(target, ".", 2)
 		if len(parts) < 2 {
-			return false
+			return nil, ErrInvalidSessionTarget
 		}
 
 		lookup = map[string]string{
@@ -228,32 +376,53 @@ func (s *Server) publicKeyHandler(ctx sshserver.Context, pubKey sshserver.Public
 	}
 
 	device, errs := c.DeviceLookup(lookup)
-	if len(errs) > 0 {
-		return false
+	if len(errs) > 0 || device == nil {
+		return nil, ErrDeviceNotFound
 	}
 
-	magicPubKey, err := ssh.NewPublicKey(&magicKey.PublicKey)
-	if err != nil {
+	return device, nil
+}
+
+// evaluateKey checks whether the public key is registered in the namespace of
+// the device and allowed to log into it.
+func evaluateKey(fingerprint string, device *models.Device) bool {
+	apiClient := client.NewClient()
+	if _, err := apiClient.GetPublicKey(fingerprint, device.TenantID); err != nil {
+		authFailures.WithLabelValues(authFailureUnknownKey).Inc()
+
 		return false
 	}
 
-	if ssh.FingerprintLegacyMD5(magicPubKey) != fingerprint {
-		apiClient := client.NewClient()
-		if _, err = apiClient.GetPublicKey(fingerprint, device.TenantID); err != nil {
-			return false
-		}
+	if ok, err := apiClient.EvaluateKey(fingerprint, device); !ok || err != nil {
+		authFailures.WithLabelValues(authFailureKeyNotAllowed).Inc()
 
-		if ok, err := apiClient.EvaluateKey(fingerprint, device); !ok || err != nil {
-			return false
-		}
+		return false
 	}
 
-	ctx.SetValue("public_key", fingerprint)
-
 	return true
 }
 
 func (s *Server) passwordHandler(ctx sshserver.Context, pass string) bool {
+	// The password is meant to the device, so the jump host connections,
+	// which are not bound to a device, are only authenticated by public key.
+	if isJumpUser(ctx.User()) {
+		return false
+	}
+
+	// The invalid target is reported by the session handler, while any other
+	// failure rejects the password, since the second factor and the limits
+	// of the device could not be checked.
+	device, err := lookupDevice(ctx.User())
+	switch {
+	case err == ErrInvalidSessionTarget:
+	case err != nil:
+		authFailures.WithLabelValues(authFailureDeviceNotFound).Inc()
+
+		return false
+	case !checkAuthLimit(ctx, authAttempt(ctx, device)) || mfaPending(ctx, device):
+		return false
+	}
+
 	// Store password in session context for later use in session handling
 	ctx.SetValue("password", pass)
 
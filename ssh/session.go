@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"crypto/rsa"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,19 +21,51 @@ import (
 	"github.com/shellhub-io/shellhub/pkg/models"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
-var ErrInvalidSessionTarget = errors.New("invalid session target")
+const agentChannelType = "auth-agent@openssh.com"
+
+// containerSeparator separates the device from the container in the target
+// of a session addressed to a container (e.g. user@namespace.device+nginx).
+const containerSeparator = "+"
+
+// serialUserPrefix is the prefix of the login user of the sessions attached
+// to a serial port of the device (e.g. serial:ttyUSB0@namespace.device).
+const serialUserPrefix = "serial:"
+
+// containerNameRegexp matches the names and IDs of the Docker containers.
+var containerNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+var (
+	ErrInvalidSessionTarget    = errors.New("invalid session target")
+	ErrAgentForwardingDisabled = errors.New("agent forwarding is disabled")
+	ErrSerialRequiresPty       = errors.New("the serial console requires a terminal (e.g. ssh -t)")
+	ErrDeviceRejected          = errors.New("the device rejected the credentials")
+)
 
 type Session struct {
 	session       sshserver.Session
 	User          string `json:"username"`
 	Target        string `json:"device_uid"`
+	Container     string `json:"container,omitempty"`
+	TenantID      string `json:"-"`
 	UID           string `json:"uid"`
 	IPAddress     string `json:"ip_address"`
 	Authenticated bool   `json:"authenticated"`
 	Lookup        map[string]string
 	Pty           bool
+	Persistent    bool   `json:"persistent"`
+	State         string `json:"state,omitempty"`
+
+	// connID identifies the connection to the agent, which differs from the
+	// UID when reattaching to a persistent session.
+	connID   string
+	device   *models.Device
+	token    string
+	reattach bool
+	detached bool
+	replaced bool
 }
 
 type ConfigOptions struct {
@@ -38,25 +73,85 @@ type ConfigOptions struct {
 }
 
 func NewSession(target string, session sshserver.Session) (*Session, error) {
+	s, err := newSession(target, session.Context().(sshserver.Context), session.Environ())
+	if err != nil {
+		return nil, err
+	}
+
+	s.session = session
+
+	_, _, isPty := s.session.Pty()
+	s.Pty = isPty
+
+	// The serial console sessions are recorded as the pty sessions, which
+	// are the only ones recorded.
+	if strings.HasPrefix(s.User, serialUserPrefix) && !isPty {
+		return nil, ErrSerialRequiresPty
+	}
+
+	if isPty && !certificatePermits(session.Context().(sshserver.Context), permitPty) {
+		return nil, ErrPtyNotPermitted
+	}
+
+	// Only the pty sessions, which hold an interactive shell, are persistent
+	if isPty {
+		env := loadEnv(session.Environ())
+
+		if token, ok := env[sessionEnv]; ok {
+			uid, err := parseSessionToken(token, s.Target, s.User)
+			if err != nil {
+				return nil, err
+			}
+
+			s.UID = uid
+			s.Persistent = true
+			s.reattach = true
+		} else if persist, _ := strconv.ParseBool(env[persistEnv]); persist {
+			s.Persistent = true
+		}
+
+		if s.Persistent {
+			token, err := sessionToken(s.UID, s.Target, s.User)
+			if err != nil {
+				return nil, err
+			}
+
+			s.token = token
+		}
+	}
+
+	return s, nil
+}
+
+// newSession resolves the target device of a connection, which is not
+// necessarily bound to a SSH session channel (e.g. port forwarding only
+// connections).
+func newSession(target string, ctx sshserver.Context, environ []string) (*Session, error) {
 	parts := strings.SplitN(target, "@", 2)
 	if len(parts) != 2 {
 		return nil, ErrInvalidSessionTarget
 	}
 
+	target, container, err := splitContainer(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Session{
-		session: session,
-		UID:     session.Context().Value(sshserver.ContextKeySessionID).(string),
-		User:    parts[0],
-		Target:  parts[1],
+		UID:       ctx.SessionID(),
+		connID:    ctx.SessionID(),
+		User:      parts[0],
+		Target:    target,
+		Container: container,
 	}
 
-	host, _, err := net.SplitHostPort(session.RemoteAddr().String())
+	host, _, err := net.SplitHostPort(ctx.RemoteAddr().String())
 	if err != nil {
 		return nil, err
 	}
 
 	if host == "127.0.0.1" || host == "::1" {
-		env := loadEnv(session.Environ())
+		env := loadEnv(environ)
 		if value, ok := env["IP_ADDRESS"]; ok {
 			s.IPAddress = value
 		}
@@ -81,7 +176,7 @@ func NewSession(target string, session sshserver.Session) (*Session, error) {
 			"ip_address": s.IPAddress,
 		}
 	} else {
-		parts = strings.SplitN(parts[1], ".", 2)
+		parts = strings.SplitN(s.Target, ".", 2)
 		if len(parts) < 2 {
 			return nil, ErrInvalidSessionTarget
 		}
@@ -94,12 +189,14 @@ func NewSession(target string, session sshserver.Session) (*Session, error) {
 		}
 	}
 
-	uid, errs := c.Lookup(lookup)
-	if len(errs) > 0 || uid == "" {
+	device, errs := c.DeviceLookup(lookup)
+	if len(errs) > 0 || device == nil || device.UID == "" {
 		return nil, ErrInvalidSessionTarget
 	}
 
-	s.Target = uid
+	s.device = device
+	s.Target = device.UID
+	s.TenantID = device.TenantID
 	s.Lookup = lookup
 
 	if envs.IsEnterprise() {
@@ -107,38 +204,33 @@ func NewSession(target string, session sshserver.Session) (*Session, error) {
 			return nil, ErrInvalidSessionTarget
 		}
 	}
-	_, _, isPty := s.session.Pty()
-	s.Pty = isPty
 
 	return s, nil
 }
 
+// splitContainer splits the target of a session into the device and the
+// container, which is empty when the session is addressed to the device.
+func splitContainer(target string) (string, string, error) {
+	parts := strings.SplitN(target, containerSeparator, 2)
+	if len(parts) == 1 {
+		return target, "", nil
+	}
+
+	if !containerNameRegexp.MatchString(parts[1]) {
+		return "", "", ErrInvalidSessionTarget
+	}
+
+	return parts[0], parts[1], nil
+}
+
 func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.Session, conn net.Conn) error {
 	c := client.NewClient()
 	opts := ConfigOptions{}
 	err := envconfig.Process("", &opts)
 
-	config := &ssh.ClientConfig{
-		User: s.User,
-		Auth: []ssh.AuthMethod{},
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
-	}
-
-	if key != nil {
-		signer, err := ssh.NewSignerFromKey(key)
-		if err != nil {
-			return err
-		}
-
-		config.Auth = []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		}
-	} else {
-		config.Auth = []ssh.AuthMethod{
-			ssh.Password(passwd),
-		}
+	config, err := s.clientConfig(passwd, key)
+	if err != nil {
+		return err
 	}
 
 	sshConn, err := NewClientConnWithDeadline(conn, "tcp", config)
@@ -148,7 +240,7 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 			"err":     err,
 		}).Warning("Failed to connect to forwarding")
 
-		return err
+		return fmt.Errorf("%w: %s", ErrDeviceRejected, err)
 	}
 
 	client, err := sshConn.NewSession()
@@ -159,9 +251,24 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 		}).Error("Failed to create session for SSH Client")
 	}
 
+	if sshserver.AgentRequested(s.session) {
+		if err := s.forwardAgent(sshConn, client); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"session": s.UID,
+				"err":     err,
+			}).Warning("Failed to forward agent")
+		}
+	}
+
 	pty, winCh, isPty := s.session.Pty()
 
 	if isPty { //nolint:nestif
+		if s.Persistent {
+			if err := client.Setenv(agentPersistentSessionEnv, s.token); err != nil {
+				return err
+			}
+		}
+
 		err = client.RequestPty(pty.Term, pty.Window.Height, pty.Window.Width, ssh.TerminalModes{})
 		if err != nil {
 			return err
@@ -196,7 +303,11 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 			}
 		}()
 
+		outputDone := make(chan struct{})
+
 		go func() {
+			defer close(outputDone)
+
 			buf := make([]byte, 1024)
 			n, err := stdout.Read(buf)
 			waitingString := ""
@@ -233,11 +344,20 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 			}
 		}()
 
-		if err = client.Shell(); err != nil {
+		// A command requested along with a pty (e.g. ssh -t device htop) is
+		// executed instead of the login shell.
+		if command := s.session.RawCommand(); command != "" {
+			err = client.Start(command)
+		} else {
+			err = client.Shell()
+		}
+
+		if err != nil {
 			return err
 		}
 
-		disconnected := make(chan bool)
+		disconnected := make(chan bool, 1)
+		exited := make(chan error, 1)
 
 		serverConn := session.Context().Value(sshserver.ContextKeyConn).(*ssh.ServerConn)
 
@@ -251,11 +371,24 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 		}()
 
 		go func() {
-			client.Wait() // nolint:errcheck
-			disconnected <- true
+			exited <- client.Wait()
 		}()
 
-		<-disconnected
+		select {
+		case <-disconnected:
+			// The agent keeps the persistent session running until the user
+			// reattaches to it.
+			s.detached = s.Persistent
+		case err := <-exited:
+			<-outputDone
+
+			// The agent closes a persistent session without an exit status
+			// when another connection is attached to it.
+			var missingErr *ssh.ExitMissingError
+			s.replaced = s.Persistent && errors.As(err, &missingErr)
+
+			exitSession(session, err)
+		}
 
 		serverConn.Close()
 		conn.Close()
@@ -265,49 +398,148 @@ func (s *Session) connect(passwd string, key *rsa.PrivateKey, session sshserver.
 			return errs[0]
 		}
 
+		client.Stderr = session.Stderr()
 		stdin, _ := client.StdinPipe()
 		stdout, _ := client.StdoutPipe()
 
-		done := make(chan bool)
+		outputDone := make(chan struct{})
 
 		go func() {
-			if _, err = io.Copy(stdin, session); err != nil {
+			if _, err := io.Copy(stdin, session); err != nil {
 				logrus.WithFields(logrus.Fields{
 					"session": s.UID,
 					"err":     err,
 				}).Error("Failed to copy to stdin in raw session")
 			}
 
-			done <- true
+			// Forward the end of the input, so commands reading it until EOF
+			// are able to finish.
+			stdin.Close()
 		}()
 
 		go func() {
-			if _, err = io.Copy(session, stdout); err != nil {
+			defer close(outputDone)
+
+			if _, err := io.Copy(session, stdout); err != nil {
 				logrus.WithFields(logrus.Fields{
 					"session": s.UID,
 					"err":     err,
 				}).Error("Failed to copy from stdout in raw session")
 			}
-
-			done <- true
 		}()
 
-		err = client.Start(s.session.RawCommand())
+		if subsystem := s.session.Subsystem(); subsystem != "" {
+			err = client.RequestSubsystem(subsystem)
+		} else {
+			err = client.Start(s.session.RawCommand())
+		}
+
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
 				"session": s.UID,
 				"err":     err,
 			}).Error("Failed to start session raw command")
 
+			session.Exit(255) // nolint:errcheck
+
 			return nil
 		}
 
-		<-done
+		<-outputDone
+
+		exitSession(session, client.Wait())
 	}
 
 	return nil
 }
 
+// exit-signal request payload as specified in RFC4254, Section 6.10
+type exitSignalMsg struct {
+	Signal     string
+	CoreDumped bool
+	Error      string
+	Lang       string
+}
+
+// exitSession reports to the user how the command exited on the device and
+// closes the session. When the device does not report it, as older agents do,
+// the session is left to be closed with the default exit status.
+func exitSession(session sshserver.Session, err error) {
+	var exitErr *ssh.ExitError
+
+	switch {
+	case err == nil:
+		session.Exit(0) // nolint:errcheck
+	case errors.As(err, &exitErr) && exitErr.Signal() != "":
+		session.SendRequest("exit-signal", false, ssh.Marshal(&exitSignalMsg{ // nolint:errcheck
+			Signal: exitErr.Signal(),
+			Error:  exitErr.Msg(),
+			Lang:   exitErr.Lang(),
+		}))
+
+		session.Close()
+	case errors.As(err, &exitErr):
+		session.Exit(exitErr.ExitStatus()) // nolint:errcheck
+	}
+}
+
+// forwardAgent relays the agent channels opened by the device to the user,
+// as long as agent forwarding is enabled in the namespace of the device and
+// permitted by the certificate of the user, if any.
+func (s *Session) forwardAgent(conn *ssh.Client, session *ssh.Session) error {
+	if !certificatePermits(s.session.Context().(sshserver.Context), permitAgentForwarding) {
+		return ErrAgentForwardingNotPermitted
+	}
+
+	namespace, err := client.NewClient().GetNamespace(s.TenantID)
+	if err != nil {
+		return err
+	}
+
+	if namespace.Settings == nil || !namespace.Settings.AgentForwarding {
+		return ErrAgentForwardingDisabled
+	}
+
+	serverConn := s.session.Context().Value(sshserver.ContextKeyConn).(*ssh.ServerConn)
+
+	channels := conn.HandleChannelOpen(agentChannelType)
+
+	go func() {
+		for newChan := range channels {
+			go forwardChannel(serverConn, newChan)
+		}
+	}()
+
+	return agent.RequestAgentForwarding(session)
+}
+
+// clientConfig returns the configuration used to authenticate on the device
+// on behalf of the user, either with the password or the private key.
+func (s *Session) clientConfig(passwd string, key *rsa.PrivateKey) (*ssh.ClientConfig, error) {
+	config := &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            []ssh.AuthMethod{},
+		HostKeyCallback: deviceHostKeyCallback(s.device),
+	}
+
+	if key != nil {
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Auth = []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		}
+	} else {
+		config.Auth = []ssh.AuthMethod{
+			ssh.Password(passwd),
+		}
+	}
+
+	return config, nil
+}
+
 func (s *Session) register(_ sshserver.Session) error {
 	if _, _, errs := gorequest.New().Post("http://api:8080/internal/sessions").Send(*s).End(); len(errs) > 0 {
 		return errs[0]
@@ -3,8 +3,10 @@ package main
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -29,9 +31,18 @@ func HandlerWebsocket(ws *websocket.Conn) {
 	cols, _ := strconv.Atoi(ws.Request().URL.Query().Get("cols"))
 	rows, _ := strconv.Atoi(ws.Request().URL.Query().Get("rows"))
 
+	// The connection is made to the SSH server of the gateway itself.
+	hostKey, err := gatewayHostKey()
+	if err != nil {
+		fmt.Println(err) //nolint:forbidigo
+		ws.Close()
+
+		return
+	}
+
 	config := &ssh.ClientConfig{
 		User:            user,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
 	}
 
 	if fingerprint != "" && signature != "" { //nolint:nestif
@@ -92,19 +103,16 @@ func HandlerWebsocket(ws *websocket.Conn) {
 			return
 		}
 
-		signer, err := ssh.NewSignerFromKey(magicKey)
-		if err != nil {
-			ws.Close()
-
-			return
-		}
-
-		config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
+		config.Auth = []ssh.AuthMethod{ssh.PublicKeys(magicKey)}
 	} else {
 		config.Auth = []ssh.AuthMethod{ssh.Password(passwd)}
 	}
 
-	client, err := ssh.Dial("tcp", "localhost:2222", config)
+	// The second factor of the namespaces requiring MFA is prompted on the
+	// terminal before the password or the public key authentication.
+	config.Auth = append([]ssh.AuthMethod{ssh.KeyboardInteractive(mfaRelay(ws))}, config.Auth...)
+
+	tcpConn, err := net.Dial("tcp", "localhost:2222")
 	if err != nil {
 		fmt.Println(err) //nolint:forbidigo
 		ws.Close()
@@ -112,6 +120,22 @@ func HandlerWebsocket(ws *websocket.Conn) {
 		return
 	}
 
+	// The connection to the SSH server comes from the gateway itself, so the
+	// address of the web client is told to its handlers to limit the failures.
+	unregister := registerWebClient(tcpConn.LocalAddr(), ws.Request().Header.Get("X-Real-Ip"))
+	defer unregister()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(tcpConn, "localhost:2222", config)
+	if err != nil {
+		fmt.Println(err) //nolint:forbidigo
+		tcpConn.Close()
+		ws.Close()
+
+		return
+	}
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+
 	modes := ssh.TerminalModes{
 		ssh.ECHO:          1,
 		ssh.TTY_OP_ISPEED: 14400,
@@ -188,6 +212,80 @@ func HandlerWebsocket(ws *websocket.Conn) {
 	<-doneCh
 }
 
+// mfaRelay answers the keyboard-interactive challenges of the gateway with the
+// lines typed on the web terminal.
+func mfaRelay(ws *websocket.Conn) ssh.KeyboardInteractiveChallenge {
+	return func(_, instruction string, questions []string, echos []bool) ([]string, error) {
+		if instruction != "" {
+			if _, err := ws.Write([]byte(instruction + "\r\n")); err != nil {
+				return nil, err
+			}
+		}
+
+		answers := make([]string, len(questions))
+		for i, question := range questions {
+			if _, err := ws.Write([]byte(question)); err != nil {
+				return nil, err
+			}
+
+			answer, err := readLine(ws, echos[i])
+			if err != nil {
+				return nil, err
+			}
+
+			answers[i] = answer
+		}
+
+		return answers, nil
+	}
+}
+
+// maxLineLength is the maximum length of the lines read from the terminal.
+const maxLineLength = 256
+
+// ErrLineInterrupted is returned when the user interrupts the line (Ctrl+C).
+var ErrLineInterrupted = errors.New("line interrupted")
+
+// readLine reads a line typed on the web terminal, which sends the keys as
+// they are pressed, echoing them back if requested.
+func readLine(ws *websocket.Conn, echo bool) (string, error) {
+	var line []byte
+
+	buf := make([]byte, 1)
+	for {
+		if _, err := ws.Read(buf); err != nil {
+			return "", err
+		}
+
+		switch c := buf[0]; c {
+		case '\r', '\n':
+			ws.Write([]byte("\r\n")) // nolint:errcheck
+
+			return string(line), nil
+		case 0x03:
+			ws.Write([]byte("^C\r\n")) // nolint:errcheck
+
+			return "", ErrLineInterrupted
+		case 0x7f, '\b':
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				if echo {
+					ws.Write([]byte("\b \b")) // nolint:errcheck
+				}
+			}
+		default:
+			if c < ' ' || len(line) >= maxLineLength {
+				continue
+			}
+
+			line = append(line, c)
+			if echo {
+				ws.Write(buf) // nolint:errcheck
+			}
+		}
+	}
+}
+
 func redirToWs(rd io.Reader, ws *websocket.Conn) error {
 	var buf [32 * 1024]byte
 	var start, end, buflen int
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+var ErrNoTokenKey = errors.New("no key to sign the tokens")
+
+var (
+	signingKeys   = make(map[string][]byte)
+	signingKeysMu sync.Mutex
+)
+
+// signingKey returns the key signing the tokens issued by the gateway for the
+// purpose. It is derived from the host key, so it is shared by all the gateway
+// replicas, and no token can be issued or accepted without it.
+func signingKey(purpose string) ([]byte, error) {
+	signingKeysMu.Lock()
+	defer signingKeysMu.Unlock()
+
+	if key, ok := signingKeys[purpose]; ok {
+		return key, nil
+	}
+
+	data, err := ioutil.ReadFile(os.Getenv("PRIVATE_KEY"))
+	if err != nil || len(data) == 0 {
+		return nil, ErrNoTokenKey
+	}
+
+	mac := hmac.New(sha256.New, data)
+	mac.Write([]byte(purpose)) // nolint:errcheck
+
+	signingKeys[purpose] = mac.Sum(nil)
+
+	return signingKeys[purpose], nil
+}